@@ -15,7 +15,6 @@ import * as cr from 'aws-cdk-lib/custom-resources';
 import * as sfn from 'aws-cdk-lib/aws-stepfunctions';
 import * as tasks from 'aws-cdk-lib/aws-stepfunctions-tasks';
 import * as sns from 'aws-cdk-lib/aws-sns';
-import * as snsSubscriptions from 'aws-cdk-lib/aws-sns-subscriptions';
 import * as cloudfront from 'aws-cdk-lib/aws-cloudfront';
 import * as origins from 'aws-cdk-lib/aws-cloudfront-origins';
 import * as s3 from 'aws-cdk-lib/aws-s3';
@@ -186,6 +185,17 @@ export class WallcrawlerStack extends cdk.Stack {
             removalPolicy: cdk.RemovalPolicy.DESTROY,
         });
 
+        // Page console, exception and network logs captured by the controller
+        const sessionLogsTable = new dynamodb.Table(this, 'SessionLogsTable', {
+            tableName: 'wallcrawler-session-logs',
+            partitionKey: { name: 'sessionId', type: dynamodb.AttributeType.STRING },
+            sortKey: { name: 'logId', type: dynamodb.AttributeType.STRING },
+            billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+            timeToLiveAttribute: 'expiresAt', // Logs are kept for 30 days
+            pointInTimeRecovery: true,
+            removalPolicy: cdk.RemovalPolicy.DESTROY,
+        });
+
         const contextsBucket = new s3.Bucket(this, 'ContextsBucket', {
             encryption: s3.BucketEncryption.S3_MANAGED,
             blockPublicAccess: s3.BlockPublicAccess.BLOCK_ALL,
@@ -268,6 +278,7 @@ export class WallcrawlerStack extends cdk.Stack {
             ],
             environment: {
                 SESSIONS_TABLE_NAME: sessionsTable.tableName,
+                SESSION_LOGS_TABLE_NAME: sessionLogsTable.tableName,
                 ECS_CLUSTER: ecsCluster.clusterName,
                 // Use task definition family name instead of ARN to avoid circular reference
                 ECS_TASK_DEFINITION_FAMILY: 'wallcrawler-browser',
@@ -276,6 +287,11 @@ export class WallcrawlerStack extends cdk.Stack {
                 CDP_PROXY_PORT: '9223',
                 CDP_DISCONNECT_TIMEOUT: '120', // 2 minutes in seconds
                 CDP_HEALTH_CHECK_INTERVAL: '10', // Check every 10 seconds
+                USAGE_FLUSH_INTERVAL: '60', // Flush metered traffic to DynamoDB every minute
+                CHROME_MAX_RESTARTS: '3', // Restart a crashed browser up to 3 times per session
+                RESOURCE_SAMPLE_INTERVAL: '15', // Sample CPU and memory every 15 seconds
+                LOG_FLUSH_INTERVAL: '5', // Ship captured page logs every 5 seconds
+                RECORDING_FLUSH_INTERVAL: '10', // Upload recorded DOM events every 10 seconds
             },
             logging: ecs.LogDrivers.awsLogs({
                 streamPrefix: 'wallcrawler-controller',
@@ -336,6 +352,7 @@ export class WallcrawlerStack extends cdk.Stack {
         // Common Lambda environment variables
         const commonLambdaEnvironment = {
             SESSIONS_TABLE_NAME: sessionsTable.tableName,
+            SESSION_LOGS_TABLE_NAME: sessionLogsTable.tableName,
             PROJECTS_TABLE_NAME: projectsTable.tableName,
             API_KEYS_TABLE_NAME: apiKeysTable.tableName,
             CONTEXTS_TABLE_NAME: contextsTable.tableName,
@@ -375,13 +392,10 @@ export class WallcrawlerStack extends cdk.Stack {
         const sdkSessionsCreateLambda = createLambdaFunction(
             'SDKSessionsCreateLambda',
             'sdk/sessions-create',
-            'SDK: Create sessions synchronously',
-            1 // 1 minute timeout - waits for container to be ready
+            'SDK: Create sessions (waits for readiness unless waitForReady is false)',
+            1 // 1 minute timeout - polls the session record until the container is ready
         );
 
-        // Subscribe to SNS topic for session ready notifications
-        sessionReadyTopic.addSubscription(new snsSubscriptions.LambdaSubscription(sdkSessionsCreateLambda));
-
         const sdkSessionsListLambda = createLambdaFunction(
             'SDKSessionsListLambda',
             'sdk/sessions-list',
@@ -406,6 +420,48 @@ export class WallcrawlerStack extends cdk.Stack {
             'SDK: Update session (REQUEST_RELEASE)'
         );
 
+        const sdkSessionsCdpRecordingLambda = createLambdaFunction(
+            'SDKSessionsCdpRecordingLambda',
+            'sdk/sessions-cdp-recording',
+            'SDK: Download recorded CDP traffic'
+        );
+
+        const sdkSessionsShareLambda = createLambdaFunction(
+            'SDKSessionsShareLambda',
+            'sdk/sessions-share',
+            'SDK: Create view-only live view links'
+        );
+
+        const sdkSessionsDownloadsLambda = createLambdaFunction(
+            'SDKSessionsDownloadsLambda',
+            'sdk/sessions-downloads',
+            'SDK: List files downloaded by the browser'
+        );
+
+        const sdkSessionsUploadsLambda = createLambdaFunction(
+            'SDKSessionsUploadsLambda',
+            'sdk/sessions-uploads',
+            'SDK: Upload files into the session browser'
+        );
+
+        const sdkSessionsLogsLambda = createLambdaFunction(
+            'SDKSessionsLogsLambda',
+            'sdk/sessions-logs',
+            'SDK: Get captured session logs'
+        );
+
+        const sdkSessionsRecordingLambda = createLambdaFunction(
+            'SDKSessionsRecordingLambda',
+            'sdk/sessions-recording',
+            'SDK: Get the rrweb session recording'
+        );
+
+        const sdkSessionsArtifactsLambda = createLambdaFunction(
+            'SDKSessionsArtifactsLambda',
+            'sdk/sessions-artifacts',
+            'SDK: List session artifacts'
+        );
+
         const sdkProjectsListLambda = createLambdaFunction(
             'SDKProjectsListLambda',
             'sdk/projects-list',
@@ -517,6 +573,20 @@ export class WallcrawlerStack extends cdk.Stack {
             maxBatchingWindow: cdk.Duration.seconds(1),
         });
 
+        // Scheduled sweeper for expired sessions and orphaned browser tasks
+        const sessionsSweeperLambda = createLambdaFunction(
+            'SessionsSweeperLambda',
+            'sessions-sweeper',
+            'Time out expired sessions and stop orphaned browser tasks',
+            5 // 5 minute timeout
+        );
+
+        const sessionsSweeperRule = new events.Rule(this, 'SessionsSweeperRule', {
+            description: 'Run the session expiry sweeper every minute',
+            schedule: events.Schedule.rate(cdk.Duration.minutes(1)),
+        });
+        sessionsSweeperRule.addTarget(new targets.LambdaFunction(sessionsSweeperLambda));
+
         // Create Lambda Authorizer
         const authorizerLambda = createLambdaFunction(
             'AuthorizerLambda',
@@ -532,6 +602,8 @@ export class WallcrawlerStack extends cdk.Stack {
         const api = new apigateway.RestApi(this, 'WallcrawlerAPI', {
             restApiName: 'Wallcrawler API',
             description: 'Remote browser automation API compatible with Stagehand',
+            // Session uploads arrive as multipart bodies and must reach Lambda unmodified
+            binaryMediaTypes: ['multipart/form-data'],
             deployOptions: {
                 stageName: environment,
                 description: `${environment} stage`,
@@ -737,27 +809,45 @@ export class WallcrawlerStack extends cdk.Stack {
             { authorizer }
         );
 
-        // GET /v1/sessions/{id}/downloads - Downloads
+        // GET /v1/sessions/{id}/cdp-recording - Recorded CDP traffic (JSONL)
+        v1SessionResource.addResource('cdp-recording').addMethod('GET',
+            createAuthenticatedIntegration(sdkSessionsCdpRecordingLambda),
+            { authorizer }
+        );
+
+        // POST /v1/sessions/{id}/share - View-only live view links
+        v1SessionResource.addResource('share').addMethod('POST',
+            createAuthenticatedIntegration(sdkSessionsShareLambda),
+            { authorizer }
+        );
+
+        // GET /v1/sessions/{id}/artifacts - Screencast timelapse, CDP traffic log and other uploaded artifacts
+        v1SessionResource.addResource('artifacts').addMethod('GET',
+            createAuthenticatedIntegration(sdkSessionsArtifactsLambda),
+            { authorizer }
+        );
+
+        // GET /v1/sessions/{id}/downloads - Files downloaded by the browser
         v1SessionResource.addResource('downloads').addMethod('GET',
-            createAuthenticatedIntegration(sdkNotImplementedLambda),
+            createAuthenticatedIntegration(sdkSessionsDownloadsLambda),
             { authorizer }
         );
 
-        // GET /v1/sessions/{id}/logs - Logs
+        // GET /v1/sessions/{id}/logs - Captured console, exception, browser and network logs
         v1SessionResource.addResource('logs').addMethod('GET',
-            createAuthenticatedIntegration(sdkNotImplementedLambda),
+            createAuthenticatedIntegration(sdkSessionsLogsLambda),
             { authorizer }
         );
 
-        // GET /v1/sessions/{id}/recording - Recording
+        // GET /v1/sessions/{id}/recording - rrweb DOM recording, uploaded per page by the controller
         v1SessionResource.addResource('recording').addMethod('GET',
-            createAuthenticatedIntegration(sdkNotImplementedLambda),
+            createAuthenticatedIntegration(sdkSessionsRecordingLambda),
             { authorizer }
         );
 
-        // POST /v1/sessions/{id}/uploads - Uploads
+        // POST /v1/sessions/{id}/uploads - Files for file inputs, delivered to the controller
         v1SessionResource.addResource('uploads').addMethod('POST',
-            createAuthenticatedIntegration(sdkNotImplementedLambda),
+            createAuthenticatedIntegration(sdkSessionsUploadsLambda),
             {
                 authorizer,
                 requestValidator,
@@ -952,6 +1042,16 @@ export class WallcrawlerStack extends cdk.Stack {
             ],
         }));
 
+        // ListTasks is authorized against container instances, so scope it by cluster instead
+        lambdaExecutionRole.addToPolicy(new iam.PolicyStatement({
+            effect: iam.Effect.ALLOW,
+            actions: ['ecs:ListTasks'],
+            resources: ['*'],
+            conditions: {
+                ArnEquals: { 'ecs:cluster': ecsCluster.clusterArn },
+            },
+        }));
+
         lambdaExecutionRole.addToPolicy(new iam.PolicyStatement({
             effect: iam.Effect.ALLOW,
             actions: [
@@ -981,6 +1081,7 @@ export class WallcrawlerStack extends cdk.Stack {
                 apiKeysTable.tableArn,
                 `${apiKeysTable.tableArn}/index/*`,
                 contextsTable.tableArn,
+                sessionLogsTable.tableArn,
             ],
         }));
 
@@ -1016,16 +1117,47 @@ export class WallcrawlerStack extends cdk.Stack {
         browserTaskDefinition.addToTaskRolePolicy(new iam.PolicyStatement({
             effect: iam.Effect.ALLOW,
             actions: [
+                'dynamodb:GetItem',
+                'dynamodb:PutItem',
                 'dynamodb:UpdateItem',
             ],
             resources: [sessionsTable.tableArn],
         }));
 
+        // Controller looks up its own public IP before marking the session ready
+        browserTaskDefinition.addToTaskRolePolicy(new iam.PolicyStatement({
+            effect: iam.Effect.ALLOW,
+            actions: [
+                'ecs:DescribeTasks',
+                'ec2:DescribeNetworkInterfaces',
+            ],
+            resources: ['*'],
+        }));
+
+        // Controller ships captured page logs in batches
+        browserTaskDefinition.addToTaskRolePolicy(new iam.PolicyStatement({
+            effect: iam.Effect.ALLOW,
+            actions: [
+                'dynamodb:BatchWriteItem',
+            ],
+            resources: [sessionLogsTable.tableArn],
+        }));
+
+        // Controller records session events (e.g. human takeover) which are published to EventBridge
+        browserTaskDefinition.addToTaskRolePolicy(new iam.PolicyStatement({
+            effect: iam.Effect.ALLOW,
+            actions: [
+                'events:PutEvents',
+            ],
+            resources: ['*'],
+        }));
+
         browserTaskDefinition.addToTaskRolePolicy(new iam.PolicyStatement({
             effect: iam.Effect.ALLOW,
             actions: [
                 's3:GetObject',
                 's3:PutObject',
+                's3:DeleteObject', // Staged uploads are removed once pulled
             ],
             resources: [`${contextsBucket.bucketArn}/*`],
         }));
@@ -22,6 +22,13 @@ FUNCTIONS=(
     "cmd/sdk/sessions-retrieve:sdk/sessions-retrieve"
     "cmd/sdk/sessions-debug:sdk/sessions-debug"
     "cmd/sdk/sessions-update:sdk/sessions-update"
+    "cmd/sdk/sessions-cdp-recording:sdk/sessions-cdp-recording"
+    "cmd/sdk/sessions-share:sdk/sessions-share"
+    "cmd/sdk/sessions-downloads:sdk/sessions-downloads"
+    "cmd/sdk/sessions-uploads:sdk/sessions-uploads"
+    "cmd/sdk/sessions-logs:sdk/sessions-logs"
+    "cmd/sdk/sessions-recording:sdk/sessions-recording"
+    "cmd/sdk/sessions-artifacts:sdk/sessions-artifacts"
     "cmd/sdk/projects-list:sdk/projects-list"
     "cmd/sdk/projects-retrieve:sdk/projects-retrieve"
     "cmd/sdk/projects-usage:sdk/projects-usage"
@@ -33,6 +40,7 @@ FUNCTIONS=(
     "cmd/ecs-controller:ecs-controller"
     "cmd/ecs-task-processor:ecs-task-processor"
     "cmd/sessions-stream-processor:sessions-stream-processor"
+    "cmd/sessions-sweeper:sessions-sweeper"
     "cmd/authorizer:authorizer"
 )
 
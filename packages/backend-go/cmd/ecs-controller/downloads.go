@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// pendingDownload is a download Chrome has started but not finished
+type pendingDownload struct {
+	filename string
+	url      string
+}
+
+// downloadCapture tracks the browser's downloads until they are uploaded to S3
+type downloadCapture struct {
+	dir     string
+	mu      sync.Mutex
+	pending map[string]pendingDownload // Keyed by Chrome's download GUID
+	uploads sync.WaitGroup
+}
+
+func newDownloadCapture(sessionID string) *downloadCapture {
+	return &downloadCapture{
+		dir:     filepath.Join(os.TempDir(), fmt.Sprintf("downloads-%s", sessionID)),
+		pending: make(map[string]pendingDownload),
+	}
+}
+
+// startDownloadCapture points Chrome's downloads at the session directory and uploads
+// each completed file. It must run again after Chrome restarts.
+func (c *Controller) startDownloadCapture() error {
+	if c.contextsBucket == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.downloads.dir, 0o755); err != nil {
+		return err
+	}
+
+	conn := c.connection()
+	if conn == nil {
+		return fmt.Errorf("CDP connection is not initialized")
+	}
+
+	chromedp.ListenBrowser(conn.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *browser.EventDownloadWillBegin:
+			c.downloads.mu.Lock()
+			c.downloads.pending[ev.GUID] = pendingDownload{filename: ev.SuggestedFilename, url: ev.URL}
+			c.downloads.mu.Unlock()
+		case *browser.EventDownloadProgress:
+			switch ev.State {
+			case browser.DownloadProgressStateCompleted:
+				c.downloads.mu.Lock()
+				download := c.downloads.pending[ev.GUID]
+				delete(c.downloads.pending, ev.GUID)
+				c.downloads.mu.Unlock()
+
+				c.downloads.uploads.Add(1)
+				go func(guid string) {
+					defer c.downloads.uploads.Done()
+					c.uploadDownload(guid, download)
+				}(ev.GUID)
+			case browser.DownloadProgressStateCanceled:
+				c.downloads.mu.Lock()
+				delete(c.downloads.pending, ev.GUID)
+				c.downloads.mu.Unlock()
+			}
+		}
+	})
+
+	// allowAndName stores each file under its GUID, so names chosen by pages cannot collide or escape the directory
+	b := chromedp.FromContext(conn.ctx).Browser
+	return browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
+		WithDownloadPath(c.downloads.dir).
+		WithEventsEnabled(true).
+		Do(cdp.WithExecutor(conn.ctx, b))
+}
+
+// uploadDownload uploads a completed download to the session's downloads prefix
+func (c *Controller) uploadDownload(guid string, download pendingDownload) {
+	path := filepath.Join(c.downloads.dir, guid)
+	defer os.Remove(path)
+
+	filename := utils.SanitizeFilename(download.filename)
+	key := utils.SessionDownloadKey(c.projectID, c.sessionID, guid, filename)
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Download %s for session %s is missing: %v", guid, c.sessionID, err)
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	uploader := manager.NewUploader(c.s3Client)
+	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(c.contextsBucket),
+		Key:         aws.String(key),
+		Body:        file,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		utils.LogSessionError(c.sessionID, c.projectID, err, "upload_download", map[string]interface{}{
+			"downloadId": guid,
+			"filename":   filename,
+		})
+		return
+	}
+
+	log.Printf("Uploaded download %s (%s) for session %s to s3://%s/%s", filename, download.url, c.sessionID, c.contextsBucket, key)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/wallcrawler/backend-go/internal/cdpproxy"
+	"github.com/wallcrawler/backend-go/internal/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// memoryLimitThreshold is the share of MaxMemory at which memory enforcement starts
+const memoryLimitThreshold = 0.9
+
+// Limit names and enforcement actions recorded on ResourceLimitEnforced events
+const (
+	limitMemory   = "maxMemory"
+	limitDuration = "maxDuration"
+	limitActions  = "maxActions"
+
+	enforceCloseBackgroundTargets = "close_background_targets"
+	enforceTerminate              = "terminate"
+	enforceRefuseInput            = "refuse_input"
+)
+
+// loadResourceLimits reads the limits passed by sessions-create through the task environment
+func (c *Controller) loadResourceLimits() error {
+	raw := os.Getenv("RESOURCE_LIMITS")
+	if raw == "" {
+		return nil
+	}
+
+	var limits types.ResourceLimits
+	if err := json.Unmarshal([]byte(raw), &limits); err != nil {
+		return fmt.Errorf("invalid RESOURCE_LIMITS: %v", err)
+	}
+	c.limits = limits
+	return nil
+}
+
+// enforceActionLimit makes the CDP proxy refuse input once MaxActions commands were sent
+func (c *Controller) enforceActionLimit() {
+	if c.limits.MaxActions <= 0 {
+		return
+	}
+
+	c.cdpProxy.SetActionLimit(c.limits.MaxActions, func(count int64) {
+		c.recordLimitEnforcement(limitActions, enforceRefuseInput,
+			fmt.Sprintf("session sent %d actions, the maximum allowed", count),
+			map[string]interface{}{"actions": count, "maxActions": c.limits.MaxActions})
+	})
+}
+
+// startDurationLimit stops the session as TIMED_OUT once MaxDuration has elapsed.
+// The duration is measured from controller start, so provisioning time is not counted.
+func (c *Controller) startDurationLimit(ctx context.Context) {
+	if c.limits.MaxDuration <= 0 {
+		return
+	}
+
+	maxDuration := time.Duration(c.limits.MaxDuration) * time.Second
+	timer := time.NewTimer(maxDuration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	c.mu.Lock()
+	shuttingDown := c.shutdownRequested
+	c.mu.Unlock()
+	if shuttingDown {
+		return
+	}
+
+	log.Printf("Session %s reached its maximum duration of %v", c.sessionID, maxDuration)
+	c.recordLimitEnforcement(limitDuration, enforceTerminate,
+		fmt.Sprintf("session ran for its maximum duration of %v", maxDuration),
+		map[string]interface{}{"maxDuration": c.limits.MaxDuration})
+
+	c.cdpProxy.DisconnectClients(cdpproxy.CloseResourceLimit, "session timed out")
+	c.initiateShutdown(ctx, types.SessionStatusTimedOut)
+}
+
+// enforceMemoryLimit is called with every resource sample. Above the threshold it first
+// closes background pages; if memory is still above it on a later sample, or there was
+// nothing to close, the session is terminated.
+func (c *Controller) enforceMemoryLimit(memoryMB float64) {
+	if c.limits.MaxMemory <= 0 {
+		return
+	}
+
+	threshold := float64(c.limits.MaxMemory) * memoryLimitThreshold
+	if memoryMB < threshold {
+		c.memoryRelieved = false
+		return
+	}
+
+	detail := map[string]interface{}{
+		"memoryMB":    int(memoryMB + 0.5),
+		"thresholdMB": int(threshold + 0.5),
+		"maxMemory":   c.limits.MaxMemory,
+	}
+
+	if !c.memoryRelieved {
+		c.memoryRelieved = true
+		closed := c.closeBackgroundTargets()
+		if closed > 0 {
+			detail["closedTargets"] = closed
+			log.Printf("Session %s using %.0fMB of %dMB, closed %d background pages", c.sessionID, memoryMB, c.limits.MaxMemory, closed)
+			c.recordLimitEnforcement(limitMemory, enforceCloseBackgroundTargets,
+				fmt.Sprintf("memory usage of %.0fMB exceeded %.0fMB", memoryMB, threshold), detail)
+			return
+		}
+	}
+
+	log.Printf("Session %s using %.0fMB of %dMB, terminating", c.sessionID, memoryMB, c.limits.MaxMemory)
+	c.recordLimitEnforcement(limitMemory, enforceTerminate,
+		fmt.Sprintf("memory usage of %.0fMB still exceeded %.0fMB", memoryMB, threshold), detail)
+
+	c.cdpProxy.DisconnectClients(cdpproxy.CloseResourceLimit, "session memory limit exceeded")
+	go c.initiateShutdown(context.Background(), types.SessionStatusFailed)
+}
+
+// closeBackgroundTargets closes every page except the most recently active one
+func (c *Controller) closeBackgroundTargets() int {
+	pages, err := c.cdpProxy.Pages()
+	if err != nil {
+		log.Printf("Failed to list pages for session %s: %v", c.sessionID, err)
+		return 0
+	}
+	if len(pages) < 2 {
+		return 0
+	}
+
+	conn := c.connection()
+	if conn == nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(conn.ctx, 10*time.Second)
+	defer cancel()
+	browser := chromedp.FromContext(conn.ctx).Browser
+
+	closed := 0
+	for _, page := range pages[1:] {
+		if err := target.CloseTarget(target.ID(page.ID)).Do(cdp.WithExecutor(ctx, browser)); err != nil {
+			log.Printf("Failed to close background page %s: %v", page.ID, err)
+			continue
+		}
+		closed++
+	}
+	return closed
+}
+
+// recordLimitEnforcement logs an enforcement decision and adds a ResourceLimitEnforced event
+func (c *Controller) recordLimitEnforcement(limit, action, reason string, detail map[string]interface{}) {
+	metadata := map[string]interface{}{
+		"limit":  limit,
+		"action": action,
+	}
+	for k, v := range detail {
+		metadata[k] = v
+	}
+	utils.LogSessionError(c.sessionID, c.projectID, fmt.Errorf("resource limit enforced: %s", reason), "resource_limit", metadata)
+
+	if utils.SessionsTableName == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	eventDetail := map[string]interface{}{
+		"sessionId": c.sessionID,
+		"limit":     limit,
+		"action":    action,
+		"reason":    reason,
+	}
+	for k, v := range detail {
+		eventDetail[k] = v
+	}
+	if err := utils.AddSessionEvent(ctx, c.ddbClient, c.sessionID, "ResourceLimitEnforced", "wallcrawler.ecs-controller", eventDetail); err != nil {
+		log.Printf("Error recording resource limit enforcement for session %s: %v", c.sessionID, err)
+	}
+}
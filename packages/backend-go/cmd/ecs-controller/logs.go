@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/wallcrawler/backend-go/internal/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const (
+	maxBufferedLogs     = 5000 // Oldest entries are dropped beyond this while the store is unreachable
+	maxLogMessageLength = 2048
+	maxLogURLLength     = 1024
+)
+
+// logBuffer holds captured page logs until they are shipped to the session logs table
+type logBuffer struct {
+	mu      sync.Mutex
+	entries []types.SessionLogEntry
+	seq     int64
+	dropped int64
+}
+
+// captureLogs records console calls, exceptions, browser log entries and network activity of a page
+func (c *Controller) captureLogs(ctx context.Context, targetID target.ID) {
+	if utils.SessionLogsTableName == "" {
+		return
+	}
+
+	pageID := string(targetID)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			entry := types.SessionLogEntry{
+				Type:    types.SessionLogTypeConsole,
+				Level:   consoleLevel(ev.Type),
+				Message: formatConsoleArgs(ev.Args),
+			}
+			if ev.StackTrace != nil && len(ev.StackTrace.CallFrames) > 0 {
+				frame := ev.StackTrace.CallFrames[0]
+				entry.Source = fmt.Sprintf("%s:%d:%d", frame.URL, frame.LineNumber+1, frame.ColumnNumber+1)
+			}
+			c.appendLog(pageID, entry)
+
+		case *runtime.EventExceptionThrown:
+			details := ev.ExceptionDetails
+			message := details.Text
+			if details.Exception != nil && details.Exception.Description != "" {
+				message = details.Exception.Description
+			}
+			entry := types.SessionLogEntry{
+				Type:    types.SessionLogTypeException,
+				Level:   types.SessionLogLevelError,
+				Message: message,
+			}
+			if details.URL != "" {
+				entry.Source = fmt.Sprintf("%s:%d:%d", details.URL, details.LineNumber+1, details.ColumnNumber+1)
+			}
+			c.appendLog(pageID, entry)
+
+		case *cdplog.EventEntryAdded:
+			level := string(ev.Entry.Level)
+			if ev.Entry.Level == cdplog.LevelVerbose {
+				level = types.SessionLogLevelDebug
+			}
+			c.appendLog(pageID, types.SessionLogEntry{
+				Type:      types.SessionLogTypeBrowser,
+				Level:     level,
+				Message:   ev.Entry.Text,
+				URL:       ev.Entry.URL,
+				RequestID: string(ev.Entry.NetworkRequestID),
+				Source:    string(ev.Entry.Source),
+			})
+
+		case *network.EventRequestWillBeSent:
+			c.appendLog(pageID, types.SessionLogEntry{
+				Type:         types.SessionLogTypeNetwork,
+				Level:        types.SessionLogLevelDebug,
+				Message:      "request",
+				URL:          ev.Request.URL,
+				Method:       ev.Request.Method,
+				RequestID:    string(ev.RequestID),
+				ResourceType: string(ev.Type),
+			})
+
+		case *network.EventResponseReceived:
+			level := types.SessionLogLevelInfo
+			switch {
+			case ev.Response.Status >= 500:
+				level = types.SessionLogLevelError
+			case ev.Response.Status >= 400:
+				level = types.SessionLogLevelWarning
+			}
+			c.appendLog(pageID, types.SessionLogEntry{
+				Type:         types.SessionLogTypeNetwork,
+				Level:        level,
+				Message:      ev.Response.StatusText,
+				URL:          ev.Response.URL,
+				Status:       int(ev.Response.Status),
+				RequestID:    string(ev.RequestID),
+				ResourceType: string(ev.Type),
+			})
+
+		case *network.EventLoadingFailed:
+			level := types.SessionLogLevelError
+			if ev.Canceled {
+				level = types.SessionLogLevelWarning
+			}
+			c.appendLog(pageID, types.SessionLogEntry{
+				Type:         types.SessionLogTypeNetwork,
+				Level:        level,
+				Message:      ev.ErrorText,
+				RequestID:    string(ev.RequestID),
+				ResourceType: string(ev.Type),
+			})
+		}
+	})
+
+	if err := chromedp.Run(ctx, runtime.Enable(), cdplog.Enable(), network.Enable()); err != nil {
+		log.Printf("Failed to enable log capture on target %s: %v", targetID, err)
+	}
+}
+
+// appendLog stamps and buffers a captured entry
+func (c *Controller) appendLog(pageID string, entry types.SessionLogEntry) {
+	now := time.Now()
+
+	entry.SessionID = c.sessionID
+	entry.ProjectID = c.projectID
+	entry.PageID = pageID
+	entry.Timestamp = now.UnixMilli()
+	entry.ExpiresAt = now.Add(utils.SessionLogRetention).Unix()
+	entry.Message = truncateLogField(entry.Message, maxLogMessageLength)
+	entry.URL = truncateLogField(entry.URL, maxLogURLLength)
+	entry.Source = truncateLogField(entry.Source, maxLogURLLength)
+
+	c.logs.mu.Lock()
+	defer c.logs.mu.Unlock()
+
+	c.logs.seq++
+	entry.LogID = utils.SessionLogID(now, c.logs.seq)
+
+	if len(c.logs.entries) >= maxBufferedLogs {
+		c.logs.entries = c.logs.entries[1:]
+		c.logs.dropped++
+	}
+	c.logs.entries = append(c.logs.entries, entry)
+}
+
+// flushLogs ships the buffered entries. Entries that fail to write go back into the buffer.
+func (c *Controller) flushLogs(ctx context.Context) error {
+	c.logs.mu.Lock()
+	entries := c.logs.entries
+	c.logs.entries = nil
+	dropped := c.logs.dropped
+	c.logs.dropped = 0
+	c.logs.mu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("Dropped %d log entries for session %s while the log store was unreachable", dropped, c.sessionID)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := utils.WriteSessionLogs(ctx, c.ddbClient, entries); err != nil {
+		c.logs.mu.Lock()
+		c.logs.entries = append(entries, c.logs.entries...)
+		if overflow := len(c.logs.entries) - maxBufferedLogs; overflow > 0 {
+			c.logs.entries = c.logs.entries[overflow:]
+			c.logs.dropped += int64(overflow)
+		}
+		c.logs.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// startLogShipper periodically ships captured logs until shutdown
+func (c *Controller) startLogShipper(ctx context.Context) {
+	if utils.SessionLogsTableName == "" {
+		return
+	}
+
+	flushInterval, _ := time.ParseDuration(os.Getenv("LOG_FLUSH_INTERVAL") + "s")
+	if flushInterval == 0 {
+		flushInterval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			if c.shutdownRequested {
+				c.mu.Unlock()
+				return
+			}
+			c.mu.Unlock()
+
+			flushCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			if err := c.flushLogs(flushCtx); err != nil {
+				log.Printf("Error shipping logs for session %s: %v", c.sessionID, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// consoleLevel maps a console API call to a log level
+func consoleLevel(apiType runtime.APIType) string {
+	switch apiType {
+	case runtime.APITypeError, runtime.APITypeAssert:
+		return types.SessionLogLevelError
+	case runtime.APITypeWarning:
+		return types.SessionLogLevelWarning
+	case runtime.APITypeDebug, runtime.APITypeTrace:
+		return types.SessionLogLevelDebug
+	default:
+		return types.SessionLogLevelInfo
+	}
+}
+
+// formatConsoleArgs renders console arguments the way DevTools prints them on one line
+func formatConsoleArgs(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg.Type == runtime.TypeString && len(arg.Value) > 0:
+			var s string
+			if err := json.Unmarshal(arg.Value, &s); err == nil {
+				parts = append(parts, s)
+				continue
+			}
+			parts = append(parts, string(arg.Value))
+		case len(arg.Value) > 0:
+			parts = append(parts, string(arg.Value))
+		case arg.UnserializableValue != "":
+			parts = append(parts, string(arg.UnserializableValue))
+		case arg.Description != "":
+			parts = append(parts, arg.Description)
+		default:
+			parts = append(parts, string(arg.Type))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func truncateLogField(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max] + "…"
+}
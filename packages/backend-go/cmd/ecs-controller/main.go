@@ -23,11 +23,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/wallcrawler/backend-go/internal/cdpproxy"
+	"github.com/wallcrawler/backend-go/internal/forwardproxy"
+	"github.com/wallcrawler/backend-go/internal/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
 
 	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
@@ -35,6 +37,7 @@ import (
 
 type Controller struct {
 	sessionID         string
+	projectID         string
 	ddbClient         *dynamodb.Client
 	ecsClient         *ecs.Client
 	s3Client          *s3.Client
@@ -43,16 +46,64 @@ type Controller struct {
 	disconnectTimeout time.Duration
 	shutdownRequested bool
 	mu                sync.Mutex
-	allocator         context.Context
-	allocatorCancel   context.CancelFunc
-	ctx               context.Context
-	cancel            context.CancelFunc
 	contextID         string
 	contextsBucket    string
 	contextS3Key      string
 	contextPersist    bool
 	contextEnabled    bool
 	profileDir        string
+	cdpRecorder       *cdpproxy.Recorder
+	cdpRecordingKey   string
+
+	// Browser settings requested at session creation
+	browserSettings  types.BrowserSettings
+	xvfbCmd          *exec.Cmd
+	defaultUserAgent string
+	settingsMu       sync.Mutex
+
+	// Local forwarding proxy for the session's upstream proxies
+	upstreamProxy     *forwardproxy.Proxy
+	upstreamProxyAddr string
+
+	// Chrome supervision: crashed browsers are restarted up to maxChromeRestarts times
+	chromeExited      chan struct{} // Closed when the current Chrome process exits
+	chromeExitErr     error
+	chromeRestarts    int
+	maxChromeRestarts int
+	restartMu         sync.Mutex
+
+	// chromedp contexts of the running Chrome, replaced when Chrome restarts
+	conn   *cdpConnection
+	connMu sync.Mutex
+
+	// Page targets attached through chromedp, keyed by target ID
+	pageHooks   []pageHook
+	pageTargets map[target.ID]context.CancelFunc
+	targetsMu   sync.Mutex
+
+	// Traffic metering, flushed to the session's proxyBytes
+	networkBytes int64 // Accessed atomically
+	flushedBytes int64
+	usageMu      sync.Mutex
+
+	// CPU and memory sampling of Chrome and the container
+	resources *resourceSampler
+
+	// Browser downloads, uploaded to S3 as they complete
+	downloads *downloadCapture
+
+	// Page console, exception and network logs awaiting shipment
+	logs logBuffer
+
+	// rrweb-style DOM recording, uploaded to S3 in chunks per page
+	recording *sessionRecording
+
+	// Screencast frames assembled into a timelapse uploaded at session end
+	screencast *screencastCapture
+
+	// Resource limits requested at session creation
+	limits         types.ResourceLimits
+	memoryRelieved bool // Background pages were closed for the current memory overage
 }
 
 func main() {
@@ -61,6 +112,12 @@ func main() {
 		log.Fatal("SESSION_ID environment variable is required")
 	}
 
+	// The CDP proxy only accepts tokens issued for this session and project
+	projectID := os.Getenv("PROJECT_ID")
+	if projectID == "" {
+		log.Fatal("PROJECT_ID environment variable is required")
+	}
+
 	// Setup AWS config
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
@@ -78,9 +135,15 @@ func main() {
 	// Create controller
 	controller := &Controller{
 		sessionID:         sessionID,
+		projectID:         projectID,
 		ddbClient:         dynamodb.NewFromConfig(cfg),
 		ecsClient:         ecs.NewFromConfig(cfg),
 		disconnectTimeout: disconnectTimeout,
+		maxChromeRestarts: maxChromeRestartsFromEnv(),
+		resources:         newResourceSampler(),
+		downloads:         newDownloadCapture(sessionID),
+		recording:         newSessionRecording(),
+		screencast:        newScreencastCapture(),
 	}
 	controller.s3Client = s3.NewFromConfig(cfg)
 	controller.contextID = os.Getenv("CONTEXT_ID")
@@ -93,43 +156,124 @@ func main() {
 		controller.contextEnabled = true
 	}
 
+	if strings.EqualFold(os.Getenv("CDP_RECORDING_ENABLED"), "true") && controller.contextsBucket != "" {
+		controller.cdpRecordingKey = os.Getenv("CDP_RECORDING_S3_KEY")
+	}
+
+	// Startup failures from here on are recorded on the session as FAILED with a reason
+	if err := controller.loadBrowserSettings(); err != nil {
+		controller.failStartup("Failed to load browser settings", err)
+	}
+
+	if err := controller.loadResourceLimits(); err != nil {
+		controller.failStartup("Failed to load resource limits", err)
+	}
+
 	if err := controller.prepareContext(context.Background()); err != nil {
-		log.Fatalf("Failed to prepare browser context: %v", err)
+		controller.failStartup("Failed to prepare browser context", err)
+	}
+
+	// Upstream proxies are reached through a local proxy that adds their credentials
+	if err := controller.startUpstreamProxy(); err != nil {
+		controller.failStartup("Failed to start upstream proxy", err)
+	}
+
+	// Headful sessions render into a virtual display
+	if err := controller.startXvfb(); err != nil {
+		controller.failStartup("Failed to start virtual display", err)
 	}
 
 	// Start Chrome with remote debugging
 	if err := controller.startChrome(); err != nil {
-		log.Fatalf("Failed to start Chrome: %v", err)
+		controller.failStartup("Failed to start Chrome", err)
 	}
 
 	// Wait for Chrome to be ready
 	if err := controller.waitForChrome(); err != nil {
-		log.Fatalf("Chrome failed to start properly: %v", err)
+		controller.failStartup("Chrome failed to start properly", err)
 	}
 
 	if err := controller.initCDP(); err != nil {
-		log.Fatalf("Failed to initialize CDP connection: %v", err)
+		controller.failStartup("Failed to initialize CDP connection", err)
 	}
 
 	// Log Chrome ready status
-	log.Printf("Chrome ready for session %s on port 9222 (PID: %d)", sessionID, controller.chromeCmd.Process.Pid)
+	chromeCmd, _ := controller.currentChrome()
+	log.Printf("Chrome ready for session %s on port 9222 (PID: %d)", sessionID, chromeCmd.Process.Pid)
 
-	// Start integrated CDP proxy
+	// Start integrated CDP proxy; Start fails unless the proxy passes its health check
 	if err := controller.startCDPProxy(); err != nil {
-		log.Printf("Failed to start CDP proxy: %v", err)
-	} else {
-		log.Printf("CDP proxy ready for session %s on port 9223", sessionID)
+		controller.failStartup("Failed to start CDP proxy", err)
 	}
+	log.Printf("CDP proxy ready for session %s on port 9223", sessionID)
 
 	// Set disconnect callback
 	controller.cdpProxy.SetOnDisconnect(func() {
-		log.Printf("CDP proxy reported disconnect")
+		log.Printf("CDP proxy reported last client disconnected")
 	})
 
+	// Record human takeover as session events so automation owners can see who held control
+	controller.cdpProxy.SetOnTakeoverChange(controller.recordTakeover)
+
+	// Files uploaded through the API are pulled into the container on request
+	controller.cdpProxy.SetUploadHandler(controller.fetchUpload)
+
+	// Refuse input once the session has used its action budget
+	controller.enforceActionLimit()
+
+	// Attach to every page so per-target instrumentation follows new tabs
+	controller.onPageTarget(controller.applyBrowserSettings)
+	controller.onPageTarget(controller.watchTargetCrash)
+	controller.onPageTarget(controller.meterNetworkBytes)
+	controller.onPageTarget(controller.captureLogs)
+	controller.onPageTarget(controller.recordPage)
+	controller.onPageTarget(controller.captureScreencast)
+	if err := controller.startTargetWatcher(); err != nil {
+		log.Printf("Failed to start page target watcher: %v", err)
+	}
+
+	// Keep files the browser downloads as session artifacts
+	if err := controller.startDownloadCapture(); err != nil {
+		log.Printf("Failed to start download capture: %v", err)
+	}
+
+	// Everything clients depend on is up, so advertise the endpoints and mark the session READY
+	readyCtx, readyCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	err = controller.markReady(readyCtx)
+	readyCancel()
+	if errors.Is(err, utils.ErrSessionNotStarting) {
+		// The create request gave up on this session (or it was released) while the browser started
+		log.Printf("Session %s is no longer starting; shutting down", sessionID)
+		controller.abandonStartup()
+		os.Exit(0)
+	}
+	if err != nil {
+		controller.failStartup("Failed to mark session ready", err)
+	}
+
 	// Start health monitor
 	ctx := context.Background()
 	go controller.startHealthMonitor(ctx)
 
+	// Restart Chrome if it crashes
+	go controller.superviseChrome(ctx)
+
+	// Sample CPU and memory, and periodically flush usage
+	go controller.startResourceSampler(ctx)
+	go controller.startUsageFlusher(ctx)
+
+	// Ship captured page logs to the session logs table
+	go controller.startLogShipper(ctx)
+
+	// Upload recorded DOM events while the session runs
+	go controller.startRecordingUploader(ctx)
+
+	// Screencast whichever page is in the foreground
+	go controller.followScreencastPage(ctx)
+
+	// Stop the session once it reaches its maximum duration
+	go controller.startDurationLimit(ctx)
+
 	// Listen for session events (LLM operations)
 	go controller.listenForSessionEvents(ctx)
 
@@ -228,11 +372,16 @@ func (c *Controller) startChrome() error {
 		// Remote debugging settings - SECURITY: localhost only, proxy will handle external access
 		"--remote-debugging-port=9222",
 		"--remote-debugging-address=127.0.0.1",
-		"--headless=new",
-		"--window-size=1920,1080",
 		"--virtual-time-budget=5000",
 	}
 
+	// Window size, headless mode, user agent and language come from the session's browser settings
+	args = append(args, c.chromeSettingsArgs()...)
+
+	if c.upstreamProxyAddr != "" {
+		args = append(args, "--proxy-server=http://"+c.upstreamProxyAddr)
+	}
+
 	if c.contextEnabled && c.profileDir != "" {
 		args = append(args, fmt.Sprintf("--user-data-dir=%s", c.profileDir))
 	}
@@ -241,20 +390,28 @@ func (c *Controller) startChrome() error {
 	args = append(args, "about:blank")
 
 	// Start Chrome process
-	c.chromeCmd = exec.Command("google-chrome", args...)
+	cmd := exec.Command("google-chrome", args...)
 
 	// Set environment
-	c.chromeCmd.Env = append(os.Environ(),
+	cmd.Env = append(os.Environ(),
 		"DISPLAY=:99",
 		"CHROME_DEVEL_SANDBOX=/opt/google/chrome/chrome-sandbox",
 	)
+	cmd.Env = append(cmd.Env, c.chromeSettingsEnv()...)
 
 	// Start the process
-	if err := c.chromeCmd.Start(); err != nil {
+	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start Chrome: %v", err)
 	}
 
-	log.Printf("Chrome started with PID %d", c.chromeCmd.Process.Pid)
+	exited := make(chan struct{})
+	c.mu.Lock()
+	c.chromeCmd = cmd
+	c.chromeExited = exited
+	c.mu.Unlock()
+	go c.watchChromeProcess(cmd, exited)
+
+	log.Printf("Chrome started with PID %d", cmd.Process.Pid)
 	return nil
 }
 
@@ -276,14 +433,45 @@ func (c *Controller) waitForChrome() error {
 	return fmt.Errorf("chrome failed to start within 30 seconds")
 }
 
+// cdpConnection holds the chromedp contexts bound to one Chrome process. It is never
+// modified once published; a restart publishes a new one.
+type cdpConnection struct {
+	allocator       context.Context
+	allocatorCancel context.CancelFunc
+	ctx             context.Context // Drives the initial page
+	cancel          context.CancelFunc
+	pageTargetID    target.ID
+}
+
+// connection returns the current CDP connection, or nil before initCDP and after closeCDP
+func (c *Controller) connection() *cdpConnection {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.conn
+}
+
+// closeCDP cancels the current CDP connection's contexts
+func (c *Controller) closeCDP() {
+	c.connMu.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.connMu.Unlock()
+
+	if conn != nil {
+		conn.cancel()
+		conn.allocatorCancel()
+	}
+}
+
 func (c *Controller) initCDP() error {
 	wsURL := "ws://127.0.0.1:9222/devtools/browser"
-	c.allocator, c.allocatorCancel = chromedp.NewRemoteAllocator(context.Background(), wsURL)
-	tempCtx, tempCancel := chromedp.NewContext(c.allocator)
+	allocator, allocatorCancel := chromedp.NewRemoteAllocator(context.Background(), wsURL)
+	tempCtx, tempCancel := chromedp.NewContext(allocator)
 	defer tempCancel()
 
 	targets, err := target.GetTargets().Do(tempCtx)
 	if err != nil {
+		allocatorCancel()
 		return fmt.Errorf("failed to get targets: %v", err)
 	}
 
@@ -295,10 +483,20 @@ func (c *Controller) initCDP() error {
 		}
 	}
 	if pageTargetID == "" {
+		allocatorCancel()
 		return fmt.Errorf("no page target found")
 	}
 
-	c.ctx, c.cancel = chromedp.NewContext(c.allocator, chromedp.WithTargetID(pageTargetID))
+	ctx, cancel := chromedp.NewContext(allocator, chromedp.WithTargetID(pageTargetID))
+	c.connMu.Lock()
+	c.conn = &cdpConnection{
+		allocator:       allocator,
+		allocatorCancel: allocatorCancel,
+		ctx:             ctx,
+		cancel:          cancel,
+		pageTargetID:    pageTargetID,
+	}
+	c.connMu.Unlock()
 	return nil
 }
 
@@ -342,7 +540,7 @@ func (c *Controller) startHealthMonitor(ctx context.Context) {
 					elapsed := time.Since(*disconnectedSince)
 					if elapsed > c.disconnectTimeout {
 						log.Printf("CDP disconnected for %v, initiating self-termination", elapsed)
-						c.initiateShutdown(ctx)
+						c.initiateShutdown(ctx, types.SessionStatusStopped)
 						return
 					}
 					log.Printf("CDP disconnected for %v / %v", elapsed, c.disconnectTimeout)
@@ -352,8 +550,22 @@ func (c *Controller) startHealthMonitor(ctx context.Context) {
 	}
 }
 
-// initiateShutdown performs graceful shutdown and updates DynamoDB
-func (c *Controller) initiateShutdown(ctx context.Context) {
+// initiateShutdown performs graceful shutdown and records the session's final status in DynamoDB
+func (c *Controller) initiateShutdown(ctx context.Context, status string) {
+	c.shutdown(status, func(updateCtx context.Context) error {
+		return utils.UpdateSessionStatus(updateCtx, c.ddbClient, c.sessionID, status)
+	})
+}
+
+// initiateFailedShutdown performs graceful shutdown and marks the session FAILED with the given reason
+func (c *Controller) initiateFailedShutdown(reason string) {
+	c.shutdown(types.SessionStatusFailed, func(updateCtx context.Context) error {
+		return utils.MarkSessionFailed(updateCtx, c.ddbClient, c.sessionID, reason)
+	})
+}
+
+// shutdown runs once per controller: it records the final status through record, cleans up and exits
+func (c *Controller) shutdown(status string, record func(ctx context.Context) error) {
 	c.mu.Lock()
 	if c.shutdownRequested {
 		c.mu.Unlock()
@@ -362,34 +574,20 @@ func (c *Controller) initiateShutdown(ctx context.Context) {
 	c.shutdownRequested = true
 	c.mu.Unlock()
 
-	log.Printf("Initiating graceful shutdown for session %s", c.sessionID)
-
-	// Update session status in DynamoDB
-	tableName := os.Getenv("SESSIONS_TABLE_NAME")
-	if tableName != "" {
-		updateCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		_, err := c.ddbClient.UpdateItem(updateCtx, &dynamodb.UpdateItemInput{
-			TableName: aws.String(tableName),
-			Key: map[string]dynamotypes.AttributeValue{
-				"sessionId": &dynamotypes.AttributeValueMemberS{Value: c.sessionID},
-			},
-			UpdateExpression: aws.String("SET #status = :status, updatedAt = :now"),
-			ExpressionAttributeNames: map[string]string{
-				"#status": "status",
-			},
-			ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
-				":status": &dynamotypes.AttributeValueMemberS{Value: "STOPPED"},
-				":now":    &dynamotypes.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
-			},
-		})
+	log.Printf("Initiating graceful shutdown for session %s (%s)", c.sessionID, status)
 
-		if err != nil {
+	// Record the final status; a session that already ended (released through the API,
+	// timed out by the sweeper) keeps the status it has
+	updateCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := record(updateCtx); err != nil {
+		var transitionErr *utils.SessionTransitionError
+		if errors.As(err, &transitionErr) {
+			log.Printf("Session %s already %s; keeping its status", c.sessionID, transitionErr.From)
+		} else {
 			log.Printf("Error updating session status: %v", err)
 		}
-	} else {
-		log.Printf("Sessions table name not configured; skipping status update")
 	}
 
 	// Cleanup and exit
@@ -401,7 +599,30 @@ func (c *Controller) initiateShutdown(ctx context.Context) {
 
 func (c *Controller) startCDPProxy() error {
 	// Initialize the integrated CDP proxy
-	c.cdpProxy = cdpproxy.NewCDPProxy("127.0.0.1:9222")
+	c.cdpProxy = cdpproxy.NewCDPProxy("127.0.0.1:9222", c.sessionID, c.projectID)
+
+	// Optional controller-wide CDP method policy
+	policy, err := cdpproxy.ParsePolicy(os.Getenv("CDP_POLICY"))
+	if err != nil {
+		return err
+	}
+	if policy != nil {
+		c.cdpProxy.SetDefaultPolicy(policy)
+		log.Printf("Loaded CDP method policy for session %s", c.sessionID)
+	}
+
+	// Optional CDP traffic recording, archived to S3 at shutdown
+	if c.cdpRecordingKey != "" {
+		maxFrameBytes, _ := strconv.Atoi(os.Getenv("CDP_RECORDING_MAX_FRAME_BYTES"))
+		recorder, err := cdpproxy.NewRecorder(filepath.Join(os.TempDir(), fmt.Sprintf("cdp-%s.jsonl", c.sessionID)), maxFrameBytes)
+		if err != nil {
+			log.Printf("CDP recording disabled: %v", err)
+		} else {
+			c.cdpRecorder = recorder
+			c.cdpProxy.SetRecorder(recorder)
+			log.Printf("Recording CDP traffic for session %s to %s", c.sessionID, recorder.Path())
+		}
+	}
 
 	// Get port from environment
 	port := os.Getenv("CDP_PROXY_PORT")
@@ -418,6 +639,27 @@ func (c *Controller) startCDPProxy() error {
 	return nil
 }
 
+// recordTakeover signals the pause-automation handshake through the session's event history
+func (c *Controller) recordTakeover(state cdpproxy.TakeoverState) {
+	eventType := "HumanTakeoverEnded"
+	detail := map[string]interface{}{
+		"sessionId":        c.sessionID,
+		"automationPaused": state.Active,
+	}
+	if state.Active {
+		eventType = "HumanTakeoverStarted"
+		detail["clientId"] = state.ClientID
+		detail["startedAt"] = state.StartedAt.Format(time.RFC3339)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := utils.AddSessionEvent(ctx, c.ddbClient, c.sessionID, eventType, "wallcrawler.ecs-controller", detail); err != nil {
+		log.Printf("Failed to record %s for session %s: %v", eventType, c.sessionID, err)
+	}
+}
+
 func (c *Controller) listenForSessionEvents(ctx context.Context) {
 	// In the DynamoDB architecture, LLM operations are handled by Lambda functions
 	// The ECS controller only manages Chrome and CDP proxy
@@ -435,6 +677,11 @@ func (c *Controller) listenForSessionEvents(ctx context.Context) {
 func (c *Controller) cleanup() {
 	log.Printf("Cleaning up controller for session %s", c.sessionID)
 
+	// Chrome exits below on purpose; keep the supervisor from restarting it
+	c.mu.Lock()
+	c.shutdownRequested = true
+	c.mu.Unlock()
+
 	// Shutdown CDP proxy server
 	if c.cdpProxy != nil {
 		if err := c.cdpProxy.Stop(); err != nil {
@@ -444,12 +691,7 @@ func (c *Controller) cleanup() {
 		}
 	}
 
-	if c.cancel != nil {
-		c.cancel()
-	}
-	if c.allocatorCancel != nil {
-		c.allocatorCancel()
-	}
+	c.closeCDP()
 
 	// Note: Session status was already updated in initiateShutdown if this was a health monitor termination
 	// For other terminations (SIGTERM, etc), the ecs-task-processor will handle status updates
@@ -457,31 +699,54 @@ func (c *Controller) cleanup() {
 	log.Printf("Container cleanup completed for session %s (resources cleaned, Chrome shutdown, proxy shutdown)", c.sessionID)
 
 	// Stop Chrome process
-	if c.chromeCmd != nil && c.chromeCmd.Process != nil {
-		log.Printf("Terminating Chrome process %d", c.chromeCmd.Process.Pid)
+	c.stopChromeProcess(5 * time.Second)
+	c.stopXvfb()
 
-		// Try graceful shutdown first
-		if err := c.chromeCmd.Process.Signal(syscall.SIGTERM); err != nil {
-			log.Printf("Failed to send SIGTERM: %v", err)
+	if c.upstreamProxy != nil {
+		if err := c.upstreamProxy.Stop(); err != nil {
+			log.Printf("Upstream proxy shutdown error: %v", err)
 		}
+	}
 
-		// Wait a bit for graceful shutdown
-		done := make(chan error, 1)
-		go func() {
-			done <- c.chromeCmd.Wait()
-		}()
+	// Finish uploading downloads that completed before Chrome stopped
+	c.downloads.uploads.Wait()
+	c.removeUploads()
 
-		select {
-		case <-time.After(5 * time.Second):
-			// Force kill if not stopped gracefully
-			log.Printf("Force killing Chrome process")
-			c.chromeCmd.Process.Kill()
-		case err := <-done:
-			if err != nil {
-				log.Printf("Chrome process exited with error: %v", err)
-			} else {
-				log.Printf("Chrome process exited gracefully")
-			}
+	// Proxy and Chrome are stopped, so the metered totals are final
+	c.sampleResources()
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := c.flushUsage(flushCtx); err != nil {
+		log.Printf("error flushing usage: %v", err)
+	}
+	flushCancel()
+
+	if utils.SessionLogsTableName != "" {
+		logsCtx, logsCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := c.flushLogs(logsCtx); err != nil {
+			log.Printf("error shipping logs: %v", err)
+		}
+		logsCancel()
+	}
+
+	if c.recording.enabled && c.contextsBucket != "" {
+		recordingCtx, recordingCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := c.flushRecording(recordingCtx); err != nil {
+			log.Printf("error uploading session recording: %v", err)
+		}
+		recordingCancel()
+	}
+
+	if c.cdpRecorder != nil {
+		if err := c.uploadCDPRecording(context.Background()); err != nil {
+			log.Printf("error uploading CDP recording: %v", err)
+		} else {
+			log.Printf("Uploaded CDP recording for session %s to s3://%s/%s", c.sessionID, c.contextsBucket, c.cdpRecordingKey)
+		}
+	}
+
+	if c.screencast.key != "" && c.contextsBucket != "" {
+		if err := c.uploadScreencast(context.Background()); err != nil {
+			log.Printf("error uploading screencast: %v", err)
 		}
 	}
 
@@ -524,6 +789,28 @@ func (c *Controller) persistContext(ctx context.Context) error {
 	return err
 }
 
+func (c *Controller) uploadCDPRecording(ctx context.Context) error {
+	if err := c.cdpRecorder.Close(); err != nil {
+		return err
+	}
+	defer os.Remove(c.cdpRecorder.Path())
+
+	file, err := os.Open(c.cdpRecorder.Path())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	uploader := manager.NewUploader(c.s3Client)
+	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(c.contextsBucket),
+		Key:         aws.String(c.cdpRecordingKey),
+		Body:        file,
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	return err
+}
+
 func createTarGz(srcDir string) (string, error) {
 	archiveFile, err := os.CreateTemp("", "context-*.tar.gz")
 	if err != nil {
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// meterNetworkBytes counts the encoded bytes of every response loaded by a page. With an
+// upstream proxy, responses that came through the forwarding proxy are skipped, since the
+// proxy meters them on the wire.
+func (c *Controller) meterNetworkBytes(ctx context.Context, targetID target.ID) {
+	// Listeners run on the target's event goroutine, so the set needs no lock
+	proxied := make(map[network.RequestID]bool)
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventResponseReceived:
+			if c.viaUpstreamProxy(ev.Response) {
+				proxied[ev.RequestID] = true
+			}
+		case *network.EventLoadingFinished:
+			if proxied[ev.RequestID] {
+				delete(proxied, ev.RequestID)
+				return
+			}
+			atomic.AddInt64(&c.networkBytes, int64(ev.EncodedDataLength))
+		case *network.EventLoadingFailed:
+			delete(proxied, ev.RequestID)
+		}
+	})
+
+	if err := chromedp.Run(ctx, network.Enable()); err != nil {
+		log.Printf("Failed to enable network metering on target %s: %v", targetID, err)
+	}
+}
+
+// viaUpstreamProxy reports whether Chrome received the response through the forwarding
+// proxy. Chrome reports the proxy's address as the remote address of proxied responses.
+func (c *Controller) viaUpstreamProxy(resp *network.Response) bool {
+	if c.upstreamProxyAddr == "" || resp == nil {
+		return false
+	}
+	remote := net.JoinHostPort(resp.RemoteIPAddress, strconv.FormatInt(resp.RemotePort, 10))
+	return remote == c.upstreamProxyAddr
+}
+
+// meteredBytes returns the traffic accumulated by the CDP proxy, the forwarding proxy
+// and page responses that did not go through the forwarding proxy
+func (c *Controller) meteredBytes() int64 {
+	total := atomic.LoadInt64(&c.networkBytes)
+	if c.upstreamProxy != nil {
+		bytesIn, bytesOut := c.upstreamProxy.TrafficTotals()
+		total += bytesIn + bytesOut
+	}
+	if c.cdpProxy != nil {
+		bytesIn, bytesOut := c.cdpProxy.TrafficTotals()
+		total += bytesIn + bytesOut
+	}
+	return total
+}
+
+// flushUsage adds the traffic metered since the last flush to the session's proxyBytes
+// and writes the sampled CPU and memory totals
+func (c *Controller) flushUsage(ctx context.Context) error {
+	if utils.SessionsTableName == "" {
+		return nil
+	}
+
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+
+	if err := c.flushResourceUsage(ctx); err != nil {
+		log.Printf("Error flushing resource usage for session %s: %v", c.sessionID, err)
+	}
+
+	total := c.meteredBytes()
+	delta := total - c.flushedBytes
+	if delta <= 0 {
+		return nil
+	}
+
+	if err := utils.AddSessionProxyBytes(ctx, c.ddbClient, c.sessionID, delta); err != nil {
+		return err
+	}
+	c.flushedBytes = total
+	return nil
+}
+
+// startUsageFlusher periodically flushes metered traffic until shutdown
+func (c *Controller) startUsageFlusher(ctx context.Context) {
+	flushInterval, _ := time.ParseDuration(os.Getenv("USAGE_FLUSH_INTERVAL") + "s")
+	if flushInterval == 0 {
+		flushInterval = 60 * time.Second
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			if c.shutdownRequested {
+				c.mu.Unlock()
+				return
+			}
+			c.mu.Unlock()
+
+			flushCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			if err := c.flushUsage(flushCtx); err != nil {
+				log.Printf("Error flushing usage for session %s: %v", c.sessionID, err)
+			}
+			cancel()
+		}
+	}
+}
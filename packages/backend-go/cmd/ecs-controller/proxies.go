@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/wallcrawler/backend-go/internal/forwardproxy"
+	"github.com/wallcrawler/backend-go/internal/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// startUpstreamProxy starts the local forwarding proxy when the session has upstream proxies.
+// Chrome is pointed at it with --proxy-server, so it must run before Chrome starts.
+func (c *Controller) startUpstreamProxy() error {
+	raw := os.Getenv("PROXY_CONFIG")
+	if raw == "" {
+		return nil
+	}
+
+	var configs []types.ProxyConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return fmt.Errorf("invalid PROXY_CONFIG: %v", err)
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+
+	proxy, err := forwardproxy.New(configs)
+	if err != nil {
+		return err
+	}
+	proxy.SetOnUpstreamError(c.recordUpstreamError)
+
+	addr, err := proxy.Start()
+	if err != nil {
+		return err
+	}
+
+	c.upstreamProxy = proxy
+	c.upstreamProxyAddr = addr
+	log.Printf("Routing browser traffic for session %s through %d upstream proxies", c.sessionID, len(configs))
+	return nil
+}
+
+// recordUpstreamError reports upstream proxy failures in the session log and event history
+func (c *Controller) recordUpstreamError(failure forwardproxy.UpstreamError) {
+	metadata := map[string]interface{}{
+		"upstream": failure.Upstream,
+		"host":     failure.Host,
+		"failures": failure.Count,
+	}
+	utils.LogSessionError(c.sessionID, c.projectID, failure.Err, "proxy_upstream", metadata)
+
+	if utils.SessionsTableName == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	detail := map[string]interface{}{
+		"sessionId": c.sessionID,
+		"upstream":  failure.Upstream,
+		"host":      failure.Host,
+		"failures":  failure.Count,
+		"error":     failure.Err.Error(),
+	}
+	if err := utils.AddSessionEvent(ctx, c.ddbClient, c.sessionID, "ProxyUpstreamError", "wallcrawler.ecs-controller", detail); err != nil {
+		log.Printf("Error recording upstream proxy failure for session %s: %v", c.sessionID, err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// taskMetadata is the part of the ECS task metadata (v4) the controller needs to find itself
+type taskMetadata struct {
+	Cluster string `json:"Cluster"`
+	TaskARN string `json:"TaskARN"`
+}
+
+// loadTaskMetadata reads the task's ARN and cluster from the ECS container metadata endpoint
+func loadTaskMetadata(ctx context.Context) (*taskMetadata, error) {
+	endpoint := os.Getenv("ECS_CONTAINER_METADATA_URI_V4")
+	if endpoint == "" {
+		return nil, fmt.Errorf("ECS_CONTAINER_METADATA_URI_V4 is not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/task", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("task metadata endpoint returned status %d", resp.StatusCode)
+	}
+
+	var metadata taskMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, err
+	}
+	if metadata.TaskARN == "" {
+		return nil, fmt.Errorf("task metadata has no task ARN")
+	}
+	return &metadata, nil
+}
+
+// resolvePublicIP looks up the address clients reach this task on, through its network interface
+func (c *Controller) resolvePublicIP(ctx context.Context, task *taskMetadata) (string, error) {
+	var lastErr error
+	for attempt := 1; attempt <= 5; attempt++ {
+		result, err := c.ecsClient.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(task.Cluster),
+			Tasks:   []string{task.TaskARN},
+		})
+		if err == nil && len(result.Tasks) == 0 {
+			err = fmt.Errorf("task %s not found", task.TaskARN)
+		}
+
+		if err == nil {
+			eniID := ""
+			for _, attachment := range result.Tasks[0].Attachments {
+				if aws.ToString(attachment.Type) != "ElasticNetworkInterface" {
+					continue
+				}
+				for _, detail := range attachment.Details {
+					if aws.ToString(detail.Name) == "networkInterfaceId" {
+						eniID = aws.ToString(detail.Value)
+					}
+				}
+			}
+
+			if eniID == "" {
+				err = fmt.Errorf("no network interface attached to task %s", task.TaskARN)
+			} else {
+				var publicIP string
+				publicIP, err = utils.GetENIPublicIP(ctx, eniID)
+				if err == nil && publicIP != "" {
+					return publicIP, nil
+				}
+			}
+		}
+
+		lastErr = err
+		if attempt < 5 {
+			log.Printf("Waiting for task network address... (attempt %d/5): %v", attempt, err)
+			time.Sleep(2 * time.Second)
+		}
+	}
+
+	return "", fmt.Errorf("failed to resolve task address: %v", lastErr)
+}
+
+// markReady advertises the session's endpoints and moves it to READY. It is called once
+// Chrome, the CDP connection, the restored context and the CDP proxy are all up, so
+// clients waiting on the session can connect right away.
+func (c *Controller) markReady(ctx context.Context) error {
+	task, err := loadTaskMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	publicIP, err := c.resolvePublicIP(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	sessionState, err := utils.GetSession(ctx, c.ddbClient, c.sessionID)
+	if err != nil {
+		return err
+	}
+	if sessionState.SigningKey == nil || *sessionState.SigningKey == "" {
+		return fmt.Errorf("session has no signing key")
+	}
+
+	endpoints := utils.SessionEndpoints{
+		TaskARN:    task.TaskARN,
+		PublicIP:   publicIP,
+		ConnectURL: utils.CreateAuthenticatedCDPURL(publicIP, *sessionState.SigningKey),
+	}
+	if err := utils.MarkSessionReady(ctx, c.ddbClient, c.sessionID, endpoints); err != nil {
+		return err
+	}
+
+	var provisioningMs int64
+	if createdAt, err := time.Parse(time.RFC3339, sessionState.CreatedAt); err == nil {
+		provisioningMs = time.Since(createdAt).Milliseconds()
+	}
+	utils.LogSessionReady(c.sessionID, c.projectID, publicIP, provisioningMs)
+
+	log.Printf("Session %s is ready at %s", c.sessionID, publicIP)
+	return nil
+}
+
+// failStartup records why the session could not start, stops whatever was already
+// running and exits. The full cleanup is skipped so a half-started browser never
+// overwrites the stored context or artifacts.
+func (c *Controller) failStartup(reason string, err error) {
+	log.Printf("%s: %v", reason, err)
+
+	c.mu.Lock()
+	c.shutdownRequested = true
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	failure := fmt.Sprintf("%s: %v", reason, err)
+	if markErr := utils.MarkSessionFailed(ctx, c.ddbClient, c.sessionID, failure); markErr != nil {
+		if errors.Is(markErr, utils.ErrSessionAlreadyEnded) {
+			log.Printf("Session %s already ended; keeping its status", c.sessionID)
+		} else {
+			log.Printf("Error marking session %s as failed: %v", c.sessionID, markErr)
+		}
+	}
+	utils.LogSessionError(c.sessionID, c.projectID, err, "startup", map[string]interface{}{
+		"reason": reason,
+	})
+
+	c.abandonStartup()
+	os.Exit(1)
+}
+
+// abandonStartup stops whatever startup already launched. Unlike cleanup it saves no
+// context and uploads no artifacts, since the session never ran.
+func (c *Controller) abandonStartup() {
+	c.mu.Lock()
+	c.shutdownRequested = true
+	c.mu.Unlock()
+
+	if c.cdpProxy != nil {
+		if stopErr := c.cdpProxy.Stop(); stopErr != nil {
+			log.Printf("CDP proxy shutdown error: %v", stopErr)
+		}
+	}
+	c.closeCDP()
+	c.stopChromeProcess(5 * time.Second)
+	c.stopXvfb()
+	if c.upstreamProxy != nil {
+		if stopErr := c.upstreamProxy.Stop(); stopErr != nil {
+			log.Printf("Upstream proxy shutdown error: %v", stopErr)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Session recorder injected into every page when browserSettings.recordSession is set.
+// Emits rrweb-compatible events (Meta, FullSnapshot, IncrementalSnapshot) and hands them
+// to the controller in batches through the __wallcrawlerRecord binding.
+(() => {
+  const send = window.__wallcrawlerRecord;
+  if (window.top !== window || window.__wallcrawlerRecorder || typeof send !== 'function') {
+    return;
+  }
+  window.__wallcrawlerRecorder = true;
+
+  const EventType = { FullSnapshot: 2, IncrementalSnapshot: 3, Meta: 4 };
+  const Source = { Mutation: 0, MouseMove: 1, MouseInteraction: 2, Scroll: 3, ViewportResize: 4, Input: 5 };
+  const Interaction = { MouseUp: 0, MouseDown: 1, Click: 2, ContextMenu: 3, DblClick: 4, Focus: 5, Blur: 6, TouchStart: 7, TouchEnd: 9 };
+  const SVG_NS = 'http://www.w3.org/2000/svg';
+  const URL_ATTRIBUTES = new Set(['src', 'href', 'action', 'poster']);
+
+  const ids = new WeakMap();
+  let nextId = 1;
+  const idOf = (node) => ids.get(node);
+
+  let queue = [];
+  const emit = (type, data) => queue.push({ type, data, timestamp: Date.now() });
+  const flush = () => {
+    if (queue.length === 0) return;
+    const batch = queue;
+    queue = [];
+    try {
+      send(JSON.stringify(batch));
+    } catch (e) {
+      // The binding goes away with the target; nothing left to deliver to
+    }
+  };
+
+  const absoluteURL = (value) => {
+    try {
+      return new URL(value, document.baseURI).href;
+    } catch (e) {
+      return value;
+    }
+  };
+
+  const attributeValue = (name, value) => (URL_ATTRIBUTES.has(name) && value ? absoluteURL(value) : value);
+
+  const isPassword = (el) => el.tagName === 'INPUT' && (el.type || '').toLowerCase() === 'password';
+  const maskValue = (el, value) => (isPassword(el) ? '*'.repeat(String(value).length) : value);
+
+  // serialize assigns fresh ids to a subtree and returns it in rrweb's snapshot format
+  const serialize = (node) => {
+    const id = nextId++;
+    ids.set(node, id);
+
+    switch (node.nodeType) {
+      case Node.DOCUMENT_NODE:
+        return { type: 0, id, childNodes: serializeChildren(node) };
+      case Node.DOCUMENT_TYPE_NODE:
+        return { type: 1, id, name: node.name, publicId: node.publicId, systemId: node.systemId };
+      case Node.ELEMENT_NODE: {
+        const isSVG = node.namespaceURI === SVG_NS;
+        const tagName = isSVG ? node.tagName : node.tagName.toLowerCase();
+        const attributes = {};
+        for (const attr of Array.from(node.attributes)) {
+          if (tagName === 'script' && attr.name === 'src') continue;
+          attributes[attr.name] = attributeValue(attr.name, attr.value);
+        }
+        if (tagName === 'input' || tagName === 'textarea' || tagName === 'select') {
+          if (node.type === 'checkbox' || node.type === 'radio') {
+            attributes.checked = node.checked;
+          } else {
+            attributes.value = maskValue(node, node.value);
+          }
+        }
+        // Scripts must not run again during replay
+        const childNodes = tagName === 'script' ? [] : serializeChildren(node);
+        const serialized = { type: 2, id, tagName, attributes, childNodes };
+        if (isSVG) serialized.isSVG = true;
+        return serialized;
+      }
+      case Node.TEXT_NODE: {
+        const parent = node.parentNode;
+        const serialized = { type: 3, id, textContent: node.textContent };
+        if (parent && parent.nodeName === 'STYLE') serialized.isStyle = true;
+        return serialized;
+      }
+      case Node.CDATA_SECTION_NODE:
+        return { type: 4, id, textContent: '' };
+      case Node.COMMENT_NODE:
+        return { type: 5, id, textContent: node.textContent };
+      default:
+        return null;
+    }
+  };
+
+  const serializeChildren = (node) => {
+    const children = [];
+    for (const child of Array.from(node.childNodes)) {
+      const serialized = serialize(child);
+      if (serialized) children.push(serialized);
+    }
+    return children;
+  };
+
+  const nextSiblingId = (node) => {
+    for (let sibling = node.nextSibling; sibling; sibling = sibling.nextSibling) {
+      const id = idOf(sibling);
+      if (id) return id;
+    }
+    return null;
+  };
+
+  const onMutations = (records) => {
+    const texts = [];
+    const attributes = new Map();
+    const removes = [];
+    const added = new Set();
+
+    for (const record of records) {
+      const targetId = idOf(record.target);
+      switch (record.type) {
+        case 'characterData':
+          if (targetId) texts.push({ id: targetId, value: record.target.textContent });
+          break;
+        case 'attributes': {
+          if (!targetId) break;
+          const el = record.target;
+          const changed = attributes.get(targetId) || {};
+          changed[record.attributeName] = attributeValue(record.attributeName, el.getAttribute(record.attributeName));
+          attributes.set(targetId, changed);
+          break;
+        }
+        case 'childList':
+          for (const node of Array.from(record.removedNodes)) {
+            const id = idOf(node);
+            if (id && targetId) removes.push({ parentId: targetId, id });
+            added.delete(node);
+          }
+          for (const node of Array.from(record.addedNodes)) {
+            added.add(node);
+          }
+          break;
+      }
+    }
+
+    // Only subtree roots still in the document are serialized; nested additions are part of them
+    const roots = Array.from(added).filter((node) => {
+      if (!document.contains(node) || !idOf(node.parentNode)) return false;
+      for (let parent = node.parentNode; parent; parent = parent.parentNode) {
+        if (added.has(parent)) return false;
+      }
+      return true;
+    });
+    // Later siblings first, so every nextId refers to a node the replayer already has
+    roots.sort((a, b) => (a.compareDocumentPosition(b) & Node.DOCUMENT_POSITION_FOLLOWING ? 1 : -1));
+
+    const adds = [];
+    for (const node of roots) {
+      const serialized = serialize(node);
+      if (!serialized) continue;
+      adds.push({ parentId: idOf(node.parentNode), nextId: nextSiblingId(node), node: serialized });
+    }
+
+    if (texts.length || attributes.size || removes.length || adds.length) {
+      emit(EventType.IncrementalSnapshot, {
+        source: Source.Mutation,
+        texts,
+        attributes: Array.from(attributes, ([id, changed]) => ({ id, attributes: changed })),
+        removes,
+        adds,
+      });
+    }
+  };
+
+  // Mouse positions are sampled every 50ms and sent together; timeOffset is relative to the event
+  let positions = [];
+  let lastMove = 0;
+  const onMouseMove = (event) => {
+    const now = Date.now();
+    if (now - lastMove < 50) return;
+    lastMove = now;
+    positions.push({ x: event.clientX, y: event.clientY, id: idOf(event.target) || 0, at: now });
+  };
+  const flushPositions = () => {
+    if (positions.length === 0) return;
+    const now = Date.now();
+    emit(EventType.IncrementalSnapshot, {
+      source: Source.MouseMove,
+      positions: positions.map(({ at, ...p }) => ({ ...p, timeOffset: at - now })),
+    });
+    positions = [];
+  };
+
+  const interaction = (type) => (event) => {
+    const id = idOf(event.target);
+    if (!id) return;
+    const point = event.touches && event.touches[0] ? event.touches[0] : event;
+    emit(EventType.IncrementalSnapshot, {
+      source: Source.MouseInteraction,
+      type,
+      id,
+      x: point.clientX || 0,
+      y: point.clientY || 0,
+    });
+  };
+
+  const onScroll = (event) => {
+    const target = event.target === document ? document : event.target;
+    const id = idOf(target);
+    if (!id) return;
+    const scrolling = target === document ? document.scrollingElement || document.documentElement : target;
+    emit(EventType.IncrementalSnapshot, {
+      source: Source.Scroll,
+      id,
+      x: scrolling.scrollLeft,
+      y: scrolling.scrollTop,
+    });
+  };
+
+  const onResize = () => {
+    emit(EventType.IncrementalSnapshot, {
+      source: Source.ViewportResize,
+      width: window.innerWidth,
+      height: window.innerHeight,
+    });
+  };
+
+  const onInput = (event) => {
+    const el = event.target;
+    const id = idOf(el);
+    if (!id || !('value' in el)) return;
+    emit(EventType.IncrementalSnapshot, {
+      source: Source.Input,
+      id,
+      text: maskValue(el, el.value),
+      isChecked: !!el.checked,
+    });
+  };
+
+  const start = () => {
+    emit(EventType.Meta, { href: location.href, width: window.innerWidth, height: window.innerHeight });
+    emit(EventType.FullSnapshot, {
+      node: serialize(document),
+      initialOffset: { left: window.scrollX, top: window.scrollY },
+    });
+
+    new MutationObserver(onMutations).observe(document, {
+      childList: true,
+      subtree: true,
+      attributes: true,
+      characterData: true,
+    });
+
+    const options = { capture: true, passive: true };
+    document.addEventListener('mousemove', onMouseMove, options);
+    document.addEventListener('mouseup', interaction(Interaction.MouseUp), options);
+    document.addEventListener('mousedown', interaction(Interaction.MouseDown), options);
+    document.addEventListener('click', interaction(Interaction.Click), options);
+    document.addEventListener('contextmenu', interaction(Interaction.ContextMenu), options);
+    document.addEventListener('dblclick', interaction(Interaction.DblClick), options);
+    document.addEventListener('focus', interaction(Interaction.Focus), options);
+    document.addEventListener('blur', interaction(Interaction.Blur), options);
+    document.addEventListener('touchstart', interaction(Interaction.TouchStart), options);
+    document.addEventListener('touchend', interaction(Interaction.TouchEnd), options);
+    document.addEventListener('scroll', onScroll, options);
+    document.addEventListener('input', onInput, options);
+    document.addEventListener('change', onInput, options);
+    window.addEventListener('resize', onResize, { passive: true });
+
+    setInterval(() => {
+      flushPositions();
+      flush();
+    }, 500);
+    window.addEventListener('pagehide', () => {
+      flushPositions();
+      flush();
+    });
+  };
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', start, { once: true });
+  } else {
+    start();
+  }
+})();
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// recordingBinding is the Runtime binding recorder.js delivers event batches through
+const recordingBinding = "__wallcrawlerRecord"
+
+// maxPendingRecordingBytes caps a page's buffered events while uploads are failing
+const maxPendingRecordingBytes = 32 << 20
+
+//go:embed recorder.js
+var recorderScript string
+
+// pageRecording is the part of a page's recording not yet uploaded
+type pageRecording struct {
+	events  []json.RawMessage
+	bytes   int
+	chunks  int // Chunks uploaded so far; the next chunk's sequence number
+	dropped int
+}
+
+// sessionRecording collects rrweb events per page until they are uploaded as chunks
+type sessionRecording struct {
+	enabled bool
+	mu      sync.Mutex
+	pages   map[target.ID]*pageRecording
+	flushMu sync.Mutex // Serializes uploads so each page's chunks stay in order
+}
+
+func newSessionRecording() *sessionRecording {
+	return &sessionRecording{
+		enabled: strings.EqualFold(os.Getenv("SESSION_RECORDING_ENABLED"), "true"),
+		pages:   make(map[target.ID]*pageRecording),
+	}
+}
+
+// recordPage injects the recorder into a page and collects the events it reports
+func (c *Controller) recordPage(ctx context.Context, targetID target.ID) {
+	if !c.recording.enabled || c.contextsBucket == "" {
+		return
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if ev, ok := ev.(*runtime.EventBindingCalled); ok && ev.Name == recordingBinding {
+			c.appendRecordingEvents(targetID, ev.Payload)
+		}
+	})
+
+	err := chromedp.Run(ctx,
+		runtime.Enable(),
+		runtime.AddBinding(recordingBinding),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(recorderScript).Do(ctx)
+			return err
+		}),
+		// The current document was created before the script was registered
+		chromedp.Evaluate(recorderScript, nil),
+	)
+	if err != nil {
+		log.Printf("Failed to start session recording on target %s: %v", targetID, err)
+	}
+}
+
+// appendRecordingEvents buffers a batch of events reported by a page's recorder
+func (c *Controller) appendRecordingEvents(targetID target.ID, payload string) {
+	var events []json.RawMessage
+	if err := json.Unmarshal([]byte(payload), &events); err != nil {
+		log.Printf("Ignoring malformed recording batch from target %s: %v", targetID, err)
+		return
+	}
+
+	c.recording.mu.Lock()
+	defer c.recording.mu.Unlock()
+
+	recording, ok := c.recording.pages[targetID]
+	if !ok {
+		recording = &pageRecording{}
+		c.recording.pages[targetID] = recording
+	}
+	if recording.bytes+len(payload) > maxPendingRecordingBytes {
+		recording.dropped += len(events)
+		return
+	}
+	recording.events = append(recording.events, events...)
+	recording.bytes += len(payload)
+}
+
+// flushRecording uploads every page's buffered events as that page's next chunk.
+// Events of a failed upload stay buffered for the next flush.
+func (c *Controller) flushRecording(ctx context.Context) error {
+	c.recording.flushMu.Lock()
+	defer c.recording.flushMu.Unlock()
+
+	type pendingChunk struct {
+		targetID target.ID
+		seq      int
+		events   []json.RawMessage
+		bytes    int
+	}
+
+	c.recording.mu.Lock()
+	var chunks []pendingChunk
+	for targetID, recording := range c.recording.pages {
+		if recording.dropped > 0 {
+			log.Printf("Dropped %d recording events for page %s while uploads were failing", recording.dropped, targetID)
+			recording.dropped = 0
+		}
+		if len(recording.events) == 0 {
+			continue
+		}
+		chunks = append(chunks, pendingChunk{targetID, recording.chunks, recording.events, recording.bytes})
+		recording.events = nil
+		recording.bytes = 0
+	}
+	c.recording.mu.Unlock()
+
+	var firstErr error
+	for _, chunk := range chunks {
+		err := c.uploadRecordingChunk(ctx, chunk.targetID, chunk.seq, chunk.events)
+
+		c.recording.mu.Lock()
+		recording := c.recording.pages[chunk.targetID]
+		if err == nil {
+			recording.chunks++
+		} else {
+			recording.events = append(chunk.events, recording.events...)
+			recording.bytes += chunk.bytes
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		c.recording.mu.Unlock()
+	}
+	return firstErr
+}
+
+func (c *Controller) uploadRecordingChunk(ctx context.Context, targetID target.ID, seq int, events []json.RawMessage) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(c.contextsBucket),
+		Key:         aws.String(utils.SessionRecordingChunkKey(c.projectID, c.sessionID, string(targetID), seq)),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// startRecordingUploader periodically uploads recorded events so the recording is
+// available while the session is still running
+func (c *Controller) startRecordingUploader(ctx context.Context) {
+	if !c.recording.enabled || c.contextsBucket == "" {
+		return
+	}
+
+	flushInterval, _ := time.ParseDuration(os.Getenv("RECORDING_FLUSH_INTERVAL") + "s")
+	if flushInterval == 0 {
+		flushInterval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			if c.shutdownRequested {
+				c.mu.Unlock()
+				return
+			}
+			c.mu.Unlock()
+
+			flushCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			if err := c.flushRecording(flushCtx); err != nil {
+				log.Printf("Error uploading session recording for %s: %v", c.sessionID, err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const (
+	clockTicksPerSecond = 100 // USER_HZ, fixed at 100 for /proc on Linux
+	bytesPerMB          = 1024 * 1024
+)
+
+// Container cgroup accounting files, for cgroup v2 and v1
+const (
+	cgroupV2CPUStat       = "/sys/fs/cgroup/cpu.stat"
+	cgroupV2MemoryCurrent = "/sys/fs/cgroup/memory.current"
+	cgroupV1CPUUsage      = "/sys/fs/cgroup/cpuacct/cpuacct.usage"
+	cgroupV1MemoryUsage   = "/sys/fs/cgroup/memory/memory.usage_in_bytes"
+)
+
+// resourceSampler tracks CPU and memory of the Chrome process tree and the container
+type resourceSampler struct {
+	mu sync.Mutex
+
+	lastSampleAt time.Time
+	procTicks    map[int]uint64 // Last CPU ticks seen per Chrome process
+
+	// Browser usage reported on the session
+	cpuPercentSum float64
+	samples       int
+	peakMemoryMB  float64
+
+	// Billing totals, from the container cgroup when available
+	lastCgroupCPU float64
+	haveCgroupCPU bool
+	cpuSeconds    float64
+	memoryMBHours float64
+}
+
+func newResourceSampler() *resourceSampler {
+	s := &resourceSampler{
+		lastSampleAt: time.Now(),
+		procTicks:    make(map[int]uint64),
+	}
+	s.lastCgroupCPU, s.haveCgroupCPU = readCgroupCPUSeconds()
+	return s
+}
+
+// sampleResources takes one resource sample and logs it. It returns the memory
+// counted against the session's limit: the container's when available, otherwise Chrome's.
+func (c *Controller) sampleResources() float64 {
+	cmd, _ := c.currentChrome()
+	rootPID := 0
+	if cmd != nil && cmd.Process != nil {
+		rootPID = cmd.Process.Pid
+	}
+
+	cpuPercent, memoryMB := c.resources.sample(rootPID, time.Now())
+	utils.LogResourceMetrics(c.sessionID, c.projectID, cpuPercent, memoryMB, c.meteredBytes())
+
+	if containerMB, ok := readCgroupMemoryMB(); ok {
+		return containerMB
+	}
+	return memoryMB
+}
+
+// sample records CPU time and memory since the previous sample. It returns the
+// Chrome process tree's CPU (percent of one core) and resident memory (MB).
+func (s *resourceSampler) sample(rootPID int, now time.Time) (float64, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := now.Sub(s.lastSampleAt).Seconds()
+	s.lastSampleAt = now
+	if elapsed <= 0 {
+		return 0, 0
+	}
+
+	// Chrome process tree. Ticks are tracked per process so that restarts and
+	// exiting renderers neither double count nor go negative.
+	ticks := make(map[int]uint64)
+	var rssBytes int64
+	if rootPID > 0 {
+		for pid, stat := range chromeProcessTree(rootPID) {
+			ticks[pid] = stat.cpuTicks
+			rssBytes += stat.rssBytes
+		}
+	}
+	var deltaTicks uint64
+	for pid, t := range ticks {
+		if t > s.procTicks[pid] {
+			deltaTicks += t - s.procTicks[pid]
+		}
+	}
+	s.procTicks = ticks
+
+	chromeCPUSeconds := float64(deltaTicks) / clockTicksPerSecond
+	cpuPercent := chromeCPUSeconds / elapsed * 100
+	memoryMB := float64(rssBytes) / bytesPerMB
+
+	s.cpuPercentSum += cpuPercent
+	s.samples++
+	if memoryMB > s.peakMemoryMB {
+		s.peakMemoryMB = memoryMB
+	}
+
+	// Billing covers the whole container (Chrome, controller, Xvfb) when cgroup stats exist
+	billedCPU := chromeCPUSeconds
+	if total, ok := readCgroupCPUSeconds(); ok {
+		if s.haveCgroupCPU && total >= s.lastCgroupCPU {
+			billedCPU = total - s.lastCgroupCPU
+		}
+		s.lastCgroupCPU = total
+		s.haveCgroupCPU = true
+	}
+	billedMemoryMB := memoryMB
+	if containerMB, ok := readCgroupMemoryMB(); ok {
+		billedMemoryMB = containerMB
+	}
+
+	s.cpuSeconds += billedCPU
+	s.memoryMBHours += billedMemoryMB * elapsed / 3600
+
+	return cpuPercent, memoryMB
+}
+
+// usage returns the session totals for DynamoDB
+func (s *resourceSampler) usage() utils.SessionResourceUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	avgCPU := 0.0
+	if s.samples > 0 {
+		avgCPU = s.cpuPercentSum / float64(s.samples)
+	}
+	return utils.SessionResourceUsage{
+		AvgCPUPercent: int(avgCPU + 0.5),
+		PeakMemoryMB:  int(s.peakMemoryMB + 0.5),
+		CPUSeconds:    s.cpuSeconds,
+		MemoryMBHours: s.memoryMBHours,
+	}
+}
+
+// startResourceSampler samples resource usage until shutdown
+func (c *Controller) startResourceSampler(ctx context.Context) {
+	sampleInterval, _ := time.ParseDuration(os.Getenv("RESOURCE_SAMPLE_INTERVAL") + "s")
+	if sampleInterval == 0 {
+		sampleInterval = 15 * time.Second
+	}
+
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			if c.shutdownRequested {
+				c.mu.Unlock()
+				return
+			}
+			c.mu.Unlock()
+
+			c.enforceMemoryLimit(c.sampleResources())
+		}
+	}
+}
+
+// procStat is the CPU time and resident memory of one process
+type procStat struct {
+	ppid     int
+	cpuTicks uint64
+	rssBytes int64
+}
+
+// chromeProcessTree returns the stats of rootPID and all of its descendants
+func chromeProcessTree(rootPID int) map[int]procStat {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	all := make(map[int]procStat)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := readProcStat(pid)
+		if err != nil {
+			continue // Process exited while scanning
+		}
+		all[pid] = stat
+	}
+	return processTree(all, rootPID)
+}
+
+// processTree picks rootPID and all of its descendants out of every process's stats
+func processTree(all map[int]procStat, rootPID int) map[int]procStat {
+	children := make(map[int][]int)
+	for pid, stat := range all {
+		children[stat.ppid] = append(children[stat.ppid], pid)
+	}
+
+	tree := make(map[int]procStat)
+	queue := []int{rootPID}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		stat, ok := all[pid]
+		if !ok {
+			continue
+		}
+		tree[pid] = stat
+		queue = append(queue, children[pid]...)
+	}
+	return tree
+}
+
+// readProcStat reads /proc/{pid}/stat
+func readProcStat(pid int) (procStat, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	stat, err := parseProcStat(string(data))
+	if err != nil {
+		return procStat{}, fmt.Errorf("pid %d: %v", pid, err)
+	}
+	return stat, nil
+}
+
+// parseProcStat parses the contents of a /proc/{pid}/stat file
+func parseProcStat(content string) (procStat, error) {
+	// The command name may contain spaces and parentheses, so fields start after the last ')'
+	end := strings.LastIndexByte(content, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("malformed stat")
+	}
+	fields := strings.Fields(content[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("short stat")
+	}
+
+	// fields[0] is field 3 (state) in proc(5)
+	ppid, _ := strconv.Atoi(fields[1])
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	rssPages, _ := strconv.ParseInt(fields[21], 10, 64)
+
+	return procStat{
+		ppid:     ppid,
+		cpuTicks: utime + stime,
+		rssBytes: rssPages * int64(os.Getpagesize()),
+	}, nil
+}
+
+// readCgroupCPUSeconds returns the container's total CPU time
+func readCgroupCPUSeconds() (float64, bool) {
+	if data, err := os.ReadFile(cgroupV2CPUStat); err == nil {
+		if seconds, ok := parseCgroupCPUStat(string(data)); ok {
+			return seconds, true
+		}
+	}
+	if nsec, ok := readCgroupNumber(cgroupV1CPUUsage); ok {
+		return nsec / 1e9, true
+	}
+	return 0, false
+}
+
+// parseCgroupCPUStat returns the usage_usec total of a cgroup v2 cpu.stat file, in seconds
+func parseCgroupCPUStat(content string) (float64, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		if value, ok := strings.CutPrefix(line, "usage_usec "); ok {
+			usec, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			return usec / 1e6, err == nil
+		}
+	}
+	return 0, false
+}
+
+// readCgroupMemoryMB returns the container's current memory usage
+func readCgroupMemoryMB() (float64, bool) {
+	for _, path := range []string{cgroupV2MemoryCurrent, cgroupV1MemoryUsage} {
+		if bytes, ok := readCgroupNumber(path); ok {
+			return bytes / bytesPerMB, true
+		}
+	}
+	return 0, false
+}
+
+func readCgroupNumber(path string) (float64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	return value, err == nil
+}
+
+// flushResourceUsage writes the sampled usage totals to the session
+func (c *Controller) flushResourceUsage(ctx context.Context) error {
+	if utils.SessionsTableName == "" {
+		return nil
+	}
+	usage := c.resources.usage()
+	if c.cdpProxy != nil {
+		usage.ActionsCount = int(c.cdpProxy.ActionCount())
+	}
+	return utils.UpdateSessionResourceUsage(ctx, c.ddbClient, c.sessionID, usage)
+}
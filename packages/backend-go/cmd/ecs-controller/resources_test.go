@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProcStat(t *testing.T) {
+	pageSize := int64(os.Getpagesize())
+
+	tests := []struct {
+		name    string
+		content string
+		want    procStat
+		wantErr bool
+	}{
+		{
+			name:    "chrome renderer",
+			content: "4242 (chrome) S 4200 4200 4200 0 -1 4194560 9000 0 12 0 150 50 0 0 20 0 18 0 5000 4000000000 2560 18446744073709551615 1 1 0 0 0 0 0 4096 0 0 0 0 17 1 0 0 0 0 0\n",
+			want:    procStat{ppid: 4200, cpuTicks: 200, rssBytes: 2560 * pageSize},
+		},
+		{
+			name:    "command name with spaces and parentheses",
+			content: "77 (Chrome (Renderer) x) R 76 1 1 0 -1 0 0 0 0 0 3 4 0 0 20 0 1 0 1 1 10 0\n",
+			want:    procStat{ppid: 76, cpuTicks: 7, rssBytes: 10 * pageSize},
+		},
+		{
+			name:    "missing command name",
+			content: "77 chrome R 76",
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			content: "77 (chrome) R 76 1 1 0",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProcStat(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseProcStat() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProcStat() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseProcStat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessTree(t *testing.T) {
+	all := map[int]procStat{
+		1:   {ppid: 0},
+		100: {ppid: 1, cpuTicks: 10}, // Chrome
+		101: {ppid: 100, cpuTicks: 20},
+		102: {ppid: 100, cpuTicks: 30},
+		103: {ppid: 102, cpuTicks: 40},
+		200: {ppid: 1, cpuTicks: 50}, // Xvfb
+	}
+
+	tests := []struct {
+		name    string
+		rootPID int
+		want    []int
+	}{
+		{"root with descendants", 100, []int{100, 101, 102, 103}},
+		{"subtree", 102, []int{102, 103}},
+		{"leaf", 200, []int{200}},
+		{"exited root", 999, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := processTree(all, tt.rootPID)
+
+			want := make(map[int]procStat)
+			for _, pid := range tt.want {
+				want[pid] = all[pid]
+			}
+			if !reflect.DeepEqual(tree, want) {
+				t.Errorf("processTree(%d) = %v, want %v", tt.rootPID, tree, want)
+			}
+		})
+	}
+}
+
+func TestParseCgroupCPUStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    float64
+		wantOK  bool
+	}{
+		{
+			name:    "cgroup v2",
+			content: "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\nnr_periods 0\n",
+			want:    2.5,
+			wantOK:  true,
+		},
+		{
+			name:    "usage not first",
+			content: "user_usec 1\nusage_usec 1000\n",
+			want:    0.001,
+			wantOK:  true,
+		},
+		{
+			name:    "missing usage",
+			content: "user_usec 2000000\n",
+		},
+		{
+			name:    "malformed usage",
+			content: "usage_usec lots\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCgroupCPUStat(tt.content)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("parseCgroupCPUStat() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestReadCgroupNumber(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		want   float64
+		wantOK bool
+	}{
+		{"memory.current", write("memory.current", "268435456\n"), 268435456, true},
+		{"cpuacct.usage", write("cpuacct.usage", "1500000000"), 1500000000, true},
+		{"unlimited memory.max", write("memory.max", "max\n"), 0, false},
+		{"missing file", filepath.Join(dir, "missing"), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := readCgroupNumber(tt.path)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("readCgroupNumber() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
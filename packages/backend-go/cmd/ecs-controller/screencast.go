@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const (
+	screencastBoundary  = "wallcrawler-frame"
+	screencastQuality   = 60
+	screencastMaxWidth  = 1280
+	screencastMaxHeight = 720
+	maxScreencastBytes  = 512 << 20 // Capture stops once the timelapse reaches this size
+
+	// screencastFollowInterval is how often the foreground page is checked
+	screencastFollowInterval = time.Second
+)
+
+// screencastCapture appends deduplicated screencast frames to an MJPEG timelapse on
+// disk, which is uploaded when the session ends. Only the foreground page is
+// screencast, so the timelapse shows what a user of the browser would have seen.
+type screencastCapture struct {
+	key string // S3 key of the timelapse; empty when capture is disabled
+	fps float64
+
+	targetsMu sync.Mutex
+	targets   map[target.ID]context.Context // Attached pages that can be screencast
+	active    target.ID                     // Page currently being screencast
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	frames   int
+	lastAt   time.Time
+	lastHash [sha256.Size]byte
+	full     bool
+}
+
+func newScreencastCapture() *screencastCapture {
+	capture := &screencastCapture{targets: make(map[target.ID]context.Context)}
+	if !strings.EqualFold(os.Getenv("SCREENCAST_ENABLED"), "true") {
+		return capture
+	}
+
+	capture.key = os.Getenv("SCREENCAST_S3_KEY")
+	capture.fps, _ = strconv.ParseFloat(os.Getenv("SCREENCAST_FPS"), 64)
+	if capture.fps <= 0 || capture.fps > utils.MaxScreencastFPS {
+		capture.fps = utils.DefaultScreencastFPS
+	}
+	return capture
+}
+
+// captureScreencast makes a page available to the timelapse. Its frames are only
+// recorded while followScreencastPage has it screencasting as the foreground page.
+func (c *Controller) captureScreencast(ctx context.Context, targetID target.ID) {
+	s := c.screencast
+	if s.key == "" || c.contextsBucket == "" {
+		return
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if ev, ok := ev.(*page.EventScreencastFrame); ok {
+			// Chrome sends the next frame only after an ack, which must not be issued on the listener goroutine
+			go func() {
+				if err := chromedp.Run(ctx, page.ScreencastFrameAck(ev.SessionID)); err != nil {
+					return
+				}
+				// Frames still in flight from a page that lost the foreground are dropped
+				if s.isActive(targetID) {
+					s.addFrame(ev)
+				}
+			}()
+		}
+	})
+
+	s.targetsMu.Lock()
+	s.targets[targetID] = ctx
+	s.targetsMu.Unlock()
+
+	// Forget the page once it closes or Chrome restarts
+	go func() {
+		<-ctx.Done()
+		s.targetsMu.Lock()
+		defer s.targetsMu.Unlock()
+		if s.targets[targetID] == ctx {
+			delete(s.targets, targetID)
+			if s.active == targetID {
+				s.active = ""
+			}
+		}
+	}()
+}
+
+// followScreencastPage keeps the screencast on the foreground page, switching whenever
+// another page is activated
+func (c *Controller) followScreencastPage(ctx context.Context) {
+	if c.screencast.key == "" || c.contextsBucket == "" {
+		return
+	}
+
+	ticker := time.NewTicker(screencastFollowInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Chrome lists pages most recently active first
+		pages, err := c.cdpProxy.Pages()
+		if err != nil || len(pages) == 0 {
+			continue
+		}
+		c.switchScreencast(target.ID(pages[0].ID))
+	}
+}
+
+// switchScreencast stops the screencast on the current page and starts it on targetID
+func (c *Controller) switchScreencast(targetID target.ID) {
+	s := c.screencast
+
+	s.targetsMu.Lock()
+	if s.active == targetID {
+		s.targetsMu.Unlock()
+		return
+	}
+	next, ok := s.targets[targetID]
+	if !ok {
+		// Not attached yet; picked up on a later check
+		s.targetsMu.Unlock()
+		return
+	}
+	prev := s.targets[s.active]
+	s.active = targetID
+	s.targetsMu.Unlock()
+
+	if prev != nil {
+		if err := chromedp.Run(prev, page.StopScreencast()); err != nil && prev.Err() == nil {
+			log.Printf("Failed to stop screencast on background page: %v", err)
+		}
+	}
+
+	err := chromedp.Run(next, page.StartScreencast().
+		WithFormat(page.ScreencastFormatJpeg).
+		WithQuality(screencastQuality).
+		WithMaxWidth(screencastMaxWidth).
+		WithMaxHeight(screencastMaxHeight))
+	if err != nil {
+		log.Printf("Failed to start screencast on target %s: %v", targetID, err)
+		return
+	}
+	log.Printf("Screencasting foreground page %s for session %s", targetID, c.sessionID)
+}
+
+// isActive reports whether targetID is the page being screencast
+func (s *screencastCapture) isActive(targetID target.ID) bool {
+	s.targetsMu.Lock()
+	defer s.targetsMu.Unlock()
+	return s.active == targetID
+}
+
+// addFrame appends a frame unless it arrives faster than the fps cap allows or is
+// identical to the previous frame
+func (s *screencastCapture) addFrame(ev *page.EventScreencastFrame) {
+	at := time.Now()
+	if ev.Metadata != nil && ev.Metadata.Timestamp != nil {
+		at = ev.Metadata.Timestamp.Time()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.full || (s.file == nil && s.frames > 0) {
+		return
+	}
+	if !s.lastAt.IsZero() && at.Sub(s.lastAt) < time.Duration(float64(time.Second)/s.fps) {
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(ev.Data)
+	if err != nil {
+		return
+	}
+	hash := sha256.Sum256(data)
+	if hash == s.lastHash {
+		return
+	}
+
+	if s.file == nil {
+		file, err := os.CreateTemp("", "screencast-*.mjpeg")
+		if err != nil {
+			log.Printf("Failed to create screencast file: %v", err)
+			s.full = true
+			return
+		}
+		s.file = file
+	}
+
+	// Each frame is a multipart part carrying its capture time, so the archive plays
+	// back as MJPEG and keeps the timeline
+	var part bytes.Buffer
+	fmt.Fprintf(&part, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\nX-Timestamp: %d\r\n\r\n", screencastBoundary, len(data), at.UnixMilli())
+	part.Write(data)
+	part.WriteString("\r\n")
+
+	if s.size+int64(part.Len()) > maxScreencastBytes {
+		log.Printf("Screencast reached %d bytes after %d frames; capture stopped", s.size, s.frames)
+		s.full = true
+		return
+	}
+	if _, err := s.file.Write(part.Bytes()); err != nil {
+		log.Printf("Failed to write screencast frame: %v", err)
+		s.full = true
+		return
+	}
+
+	s.size += int64(part.Len())
+	s.frames++
+	s.lastAt = at
+	s.lastHash = hash
+}
+
+// uploadScreencast closes the timelapse and uploads it as a session artifact
+func (c *Controller) uploadScreencast(ctx context.Context) error {
+	s := c.screencast
+	s.mu.Lock()
+	file := s.file
+	frames := s.frames
+	s.file = nil
+	s.mu.Unlock()
+
+	if file == nil {
+		return nil
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "--%s--\r\n", screencastBoundary); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return err
+	}
+
+	uploader := manager.NewUploader(c.s3Client)
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(c.contextsBucket),
+		Key:         aws.String(s.key),
+		Body:        file,
+		ContentType: aws.String("multipart/x-mixed-replace; boundary=" + screencastBoundary),
+		Metadata: map[string]string{
+			"frames": strconv.Itoa(frames),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Uploaded screencast of %d frames for session %s to s3://%s/%s", frames, c.sessionID, c.contextsBucket, s.key)
+	return nil
+}
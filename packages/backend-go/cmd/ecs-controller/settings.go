@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	defaultViewportWidth  = 1920
+	defaultViewportHeight = 1080
+	xvfbDisplay           = ":99"
+	xvfbSocket            = "/tmp/.X11-unix/X99"
+)
+
+// adBlockPatterns are URL patterns blocked when browserSettings.blockAds is set
+var adBlockPatterns = []string{
+	"*://*.doubleclick.net/*",
+	"*://*.googlesyndication.com/*",
+	"*://*.googleadservices.com/*",
+	"*://*.google-analytics.com/*",
+	"*://*.googletagmanager.com/*",
+	"*://*.googletagservices.com/*",
+	"*://*.adservice.google.com/*",
+	"*://*.amazon-adsystem.com/*",
+	"*://*.adnxs.com/*",
+	"*://*.advertising.com/*",
+	"*://*.criteo.com/*",
+	"*://*.criteo.net/*",
+	"*://*.taboola.com/*",
+	"*://*.outbrain.com/*",
+	"*://*.pubmatic.com/*",
+	"*://*.rubiconproject.com/*",
+	"*://*.openx.net/*",
+	"*://*.scorecardresearch.com/*",
+	"*://*.quantserve.com/*",
+	"*://*.moatads.com/*",
+	"*://*.adsrvr.org/*",
+	"*://*.facebook.net/*/fbevents.js",
+	"*://*.hotjar.com/*",
+}
+
+// loadBrowserSettings reads the settings passed by sessions-create through the task environment
+func (c *Controller) loadBrowserSettings() error {
+	raw := os.Getenv("BROWSER_SETTINGS")
+	if raw == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(raw), &c.browserSettings); err != nil {
+		return fmt.Errorf("invalid BROWSER_SETTINGS: %v", err)
+	}
+	return nil
+}
+
+// viewportSize returns the configured viewport or the default window size
+func (c *Controller) viewportSize() (int, int) {
+	if v := c.browserSettings.Viewport; v != nil && v.Width > 0 && v.Height > 0 {
+		return v.Width, v.Height
+	}
+	return defaultViewportWidth, defaultViewportHeight
+}
+
+// chromeSettingsArgs returns the Chrome flags derived from the browser settings
+func (c *Controller) chromeSettingsArgs() []string {
+	settings := c.browserSettings
+	width, height := c.viewportSize()
+
+	args := []string{fmt.Sprintf("--window-size=%d,%d", width, height)}
+	if !settings.Headful {
+		args = append(args, "--headless=new")
+	}
+	if settings.DeviceScaleFactor > 0 {
+		args = append(args, fmt.Sprintf("--force-device-scale-factor=%g", settings.DeviceScaleFactor))
+	}
+	if settings.UserAgent != "" {
+		args = append(args, "--user-agent="+settings.UserAgent)
+	}
+	if settings.Locale != "" {
+		args = append(args, "--lang="+settings.Locale)
+	}
+	return args
+}
+
+// chromeSettingsEnv returns the environment for the Chrome process derived from the browser settings
+func (c *Controller) chromeSettingsEnv() []string {
+	var env []string
+	if c.browserSettings.Timezone != "" {
+		env = append(env, "TZ="+c.browserSettings.Timezone)
+	}
+	return env
+}
+
+// startXvfb starts a virtual display for headful Chrome and waits for it to accept connections
+func (c *Controller) startXvfb() error {
+	if !c.browserSettings.Headful {
+		return nil
+	}
+
+	width, height := c.viewportSize()
+	c.xvfbCmd = exec.Command("Xvfb", xvfbDisplay,
+		"-screen", "0", fmt.Sprintf("%dx%dx24", width, height),
+		"-nolisten", "tcp",
+	)
+	if err := c.xvfbCmd.Start(); err != nil {
+		return fmt.Errorf("failed to start Xvfb: %v", err)
+	}
+
+	for i := 0; i < 50; i++ { // Wait up to 5 seconds
+		if _, err := os.Stat(xvfbSocket); err == nil {
+			log.Printf("Xvfb started on display %s (PID: %d)", xvfbDisplay, c.xvfbCmd.Process.Pid)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return fmt.Errorf("virtual display %s not ready within 5 seconds", xvfbDisplay)
+}
+
+// stopXvfb terminates the virtual display once Chrome has exited
+func (c *Controller) stopXvfb() {
+	if c.xvfbCmd == nil || c.xvfbCmd.Process == nil {
+		return
+	}
+	if err := c.xvfbCmd.Process.Kill(); err != nil {
+		log.Printf("Failed to stop Xvfb: %v", err)
+		return
+	}
+	_ = c.xvfbCmd.Wait()
+}
+
+// emulationUserAgent returns the user agent to send with an Accept-Language override.
+// Without an explicit user agent, the browser's own is used with the headless marker removed.
+func (c *Controller) emulationUserAgent(ctx context.Context) (string, error) {
+	if c.browserSettings.UserAgent != "" {
+		return c.browserSettings.UserAgent, nil
+	}
+
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+
+	if c.defaultUserAgent == "" {
+		b := chromedp.FromContext(ctx).Browser
+		_, _, _, userAgent, _, err := browser.GetVersion().Do(cdp.WithExecutor(ctx, b))
+		if err != nil {
+			return "", err
+		}
+		c.defaultUserAgent = strings.Replace(userAgent, "HeadlessChrome", "Chrome", 1)
+	}
+	return c.defaultUserAgent, nil
+}
+
+// applyBrowserSettings applies the emulation overrides to a page target.
+// Chrome flags only cover the first window, so every target is configured here as well.
+func (c *Controller) applyBrowserSettings(ctx context.Context, targetID target.ID) {
+	settings := c.browserSettings
+	var actions []chromedp.Action
+
+	if settings.Viewport != nil || settings.DeviceScaleFactor > 0 {
+		width, height := c.viewportSize()
+		actions = append(actions, emulation.SetDeviceMetricsOverride(int64(width), int64(height), settings.DeviceScaleFactor, false))
+	}
+
+	if settings.UserAgent != "" || settings.Locale != "" {
+		userAgent, err := c.emulationUserAgent(ctx)
+		if err != nil {
+			log.Printf("Failed to resolve user agent for target %s: %v", targetID, err)
+		} else {
+			override := emulation.SetUserAgentOverride(userAgent)
+			if settings.Locale != "" {
+				override = override.WithAcceptLanguage(settings.Locale)
+			}
+			actions = append(actions, override)
+		}
+	}
+
+	if settings.Locale != "" {
+		actions = append(actions, emulation.SetLocaleOverride().WithLocale(settings.Locale))
+	}
+
+	if settings.Timezone != "" {
+		actions = append(actions, emulation.SetTimezoneOverride(settings.Timezone))
+	}
+
+	if g := settings.Geolocation; g != nil {
+		accuracy := g.Accuracy
+		if accuracy == 0 {
+			accuracy = 100
+		}
+		actions = append(actions,
+			emulation.SetGeolocationOverride().WithLatitude(g.Latitude).WithLongitude(g.Longitude).WithAccuracy(accuracy),
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				b := chromedp.FromContext(ctx).Browser
+				return browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation}).Do(cdp.WithExecutor(ctx, b))
+			}),
+		)
+	}
+
+	if settings.BlockAds {
+		actions = append(actions, network.Enable(), network.SetBlockedURLs(adBlockPatterns))
+	}
+
+	if len(actions) == 0 {
+		return
+	}
+
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		log.Printf("Failed to apply browser settings to target %s: %v", targetID, err)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/chromedp/cdproto/inspector"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/wallcrawler/backend-go/internal/cdpproxy"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const defaultMaxChromeRestarts = 3
+
+// chromeProfileLocks are left behind when Chrome is killed and would block a restart on the same profile
+var chromeProfileLocks = []string{"SingletonLock", "SingletonSocket", "SingletonCookie"}
+
+// maxChromeRestartsFromEnv reads CHROME_MAX_RESTARTS, allowing 0 to disable restarts
+func maxChromeRestartsFromEnv() int {
+	if raw := os.Getenv("CHROME_MAX_RESTARTS"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return defaultMaxChromeRestarts
+}
+
+// watchChromeProcess reaps the Chrome process and closes exited when it ends
+func (c *Controller) watchChromeProcess(cmd *exec.Cmd, exited chan struct{}) {
+	err := cmd.Wait()
+
+	c.mu.Lock()
+	c.chromeExitErr = err
+	c.mu.Unlock()
+
+	close(exited)
+}
+
+// currentChrome returns the running Chrome process and its exit channel
+func (c *Controller) currentChrome() (*exec.Cmd, chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.chromeCmd, c.chromeExited
+}
+
+// superviseChrome restarts Chrome whenever its process exits outside of shutdown
+func (c *Controller) superviseChrome(ctx context.Context) {
+	for {
+		cmd, exited := c.currentChrome()
+		select {
+		case <-ctx.Done():
+			return
+		case <-exited:
+		}
+
+		c.mu.Lock()
+		shuttingDown := c.shutdownRequested
+		exitErr := c.chromeExitErr
+		c.mu.Unlock()
+		if shuttingDown {
+			return
+		}
+
+		detail := map[string]interface{}{"pid": cmd.Process.Pid}
+		if exitErr != nil {
+			detail["exitError"] = exitErr.Error()
+		}
+		c.handleChromeCrash(cmd, "process_exit", detail)
+	}
+}
+
+// watchTargetCrash treats a crashed renderer as a browser crash, since the
+// automation client's page cannot recover from it
+func (c *Controller) watchTargetCrash(ctx context.Context, targetID target.ID) {
+	cmd, _ := c.currentChrome()
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if _, ok := ev.(*inspector.EventTargetCrashed); ok {
+			go c.handleChromeCrash(cmd, "renderer_crash", map[string]interface{}{"targetId": string(targetID)})
+		}
+	})
+
+	if err := chromedp.Run(ctx, inspector.Enable()); err != nil {
+		log.Printf("Failed to enable crash detection on target %s: %v", targetID, err)
+	}
+}
+
+// handleChromeCrash records a crash of the given Chrome process and restarts Chrome
+// while the restart limit allows, otherwise fails the session and shuts down
+func (c *Controller) handleChromeCrash(crashed *exec.Cmd, reason string, detail map[string]interface{}) {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+
+	// Several signals can report the same crash; only the first restarts the browser
+	if cmd, _ := c.currentChrome(); cmd != crashed {
+		return
+	}
+
+	c.mu.Lock()
+	shuttingDown := c.shutdownRequested
+	c.mu.Unlock()
+	if shuttingDown {
+		return
+	}
+
+	restart := c.chromeRestarts < c.maxChromeRestarts
+	if restart {
+		c.chromeRestarts++
+	}
+
+	log.Printf("Chrome crashed for session %s (%s), restart %d/%d", c.sessionID, reason, c.chromeRestarts, c.maxChromeRestarts)
+	c.recordChromeCrash(reason, restart, detail)
+
+	if !restart {
+		c.cdpProxy.DisconnectClients(cdpproxy.CloseBrowserCrashed, "browser crashed")
+		c.initiateFailedShutdown(fmt.Sprintf("chrome crashed (%s) after %d of %d restarts", reason, c.chromeRestarts, c.maxChromeRestarts))
+		return
+	}
+
+	if err := c.restartChrome(); err != nil {
+		log.Printf("Failed to restart Chrome for session %s: %v", c.sessionID, err)
+		utils.LogSessionError(c.sessionID, c.projectID, err, "restart_chrome", detail)
+		c.cdpProxy.DisconnectClients(cdpproxy.CloseBrowserCrashed, "browser restart failed")
+		c.initiateFailedShutdown(fmt.Sprintf("chrome crashed (%s) and restart %d of %d failed: %v", reason, c.chromeRestarts, c.maxChromeRestarts, err))
+		return
+	}
+
+	// Existing sockets point at the dead browser; clients reconnect to the same URL
+	closed := c.cdpProxy.DisconnectClients(cdpproxy.CloseBrowserRestarted, "browser restarted")
+	cmd, _ := c.currentChrome()
+	log.Printf("Chrome restarted for session %s (PID: %d), closed %d client connections", c.sessionID, cmd.Process.Pid, closed)
+}
+
+// recordChromeCrash bumps the session's retry count and adds a BrowserCrashed event
+func (c *Controller) recordChromeCrash(reason string, restarting bool, detail map[string]interface{}) {
+	utils.LogSessionError(c.sessionID, c.projectID, fmt.Errorf("chrome crashed: %s", reason), "chrome_crash", detail)
+
+	if utils.SessionsTableName == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := utils.IncrementSessionRetryCount(ctx, c.ddbClient, c.sessionID); err != nil {
+		log.Printf("Error incrementing retry count for session %s: %v", c.sessionID, err)
+	}
+
+	eventDetail := map[string]interface{}{
+		"sessionId":   c.sessionID,
+		"reason":      reason,
+		"restarting":  restarting,
+		"restarts":    c.chromeRestarts,
+		"maxRestarts": c.maxChromeRestarts,
+	}
+	for k, v := range detail {
+		eventDetail[k] = v
+	}
+	if err := utils.AddSessionEvent(ctx, c.ddbClient, c.sessionID, "BrowserCrashed", "wallcrawler.ecs-controller", eventDetail); err != nil {
+		log.Printf("Error recording browser crash for session %s: %v", c.sessionID, err)
+	}
+}
+
+// restartChrome replaces the Chrome process and reconnects the controller's CDP contexts
+func (c *Controller) restartChrome() error {
+	// Drop every chromedp context bound to the old browser
+	c.targetsMu.Lock()
+	for _, cancel := range c.pageTargets {
+		if cancel != nil {
+			cancel()
+		}
+	}
+	c.pageTargets = make(map[target.ID]context.CancelFunc)
+	c.targetsMu.Unlock()
+
+	c.closeCDP()
+
+	// A renderer crash leaves the browser process running
+	c.stopChromeProcess(5 * time.Second)
+
+	if c.contextEnabled && c.profileDir != "" {
+		for _, name := range chromeProfileLocks {
+			os.Remove(filepath.Join(c.profileDir, name))
+		}
+	}
+
+	if err := c.startChrome(); err != nil {
+		return err
+	}
+	if err := c.waitForChrome(); err != nil {
+		return err
+	}
+	if err := c.initCDP(); err != nil {
+		return err
+	}
+	if err := c.startTargetWatcher(); err != nil {
+		return err
+	}
+	if err := c.startDownloadCapture(); err != nil {
+		log.Printf("Failed to restart download capture for session %s: %v", c.sessionID, err)
+	}
+	return nil
+}
+
+// stopChromeProcess terminates the current Chrome process, force killing it after timeout
+func (c *Controller) stopChromeProcess(timeout time.Duration) {
+	cmd, exited := c.currentChrome()
+	if cmd == nil || cmd.Process == nil || exited == nil {
+		return
+	}
+
+	select {
+	case <-exited:
+		return
+	default:
+	}
+
+	log.Printf("Terminating Chrome process %d", cmd.Process.Pid)
+
+	// Try graceful shutdown first
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		log.Printf("Failed to send SIGTERM: %v", err)
+	}
+
+	select {
+	case <-time.After(timeout):
+		// Force kill if not stopped gracefully
+		log.Printf("Force killing Chrome process")
+		cmd.Process.Kill()
+		<-exited
+	case <-exited:
+		c.mu.Lock()
+		err := c.chromeExitErr
+		c.mu.Unlock()
+		if err != nil {
+			log.Printf("Chrome process exited with error: %v", err)
+		} else {
+			log.Printf("Chrome process exited gracefully")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// pageHook is run once for every page target the controller attaches to
+type pageHook func(ctx context.Context, targetID target.ID)
+
+// onPageTarget registers a hook for the initial page and every page opened later.
+// Hooks must be registered before startTargetWatcher is called.
+func (c *Controller) onPageTarget(hook pageHook) {
+	c.pageHooks = append(c.pageHooks, hook)
+}
+
+// startTargetWatcher attaches to page targets as Chrome creates them and runs the registered hooks
+func (c *Controller) startTargetWatcher() error {
+	conn := c.connection()
+	if conn == nil {
+		return fmt.Errorf("CDP connection is not initialized")
+	}
+
+	// Attach to the initial page so the browser connection exists
+	if err := chromedp.Run(conn.ctx); err != nil {
+		return err
+	}
+
+	c.targetsMu.Lock()
+	c.pageTargets = map[target.ID]context.CancelFunc{
+		conn.pageTargetID: nil, // The initial page is driven through conn.ctx
+	}
+	c.targetsMu.Unlock()
+
+	chromedp.ListenBrowser(conn.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *target.EventTargetCreated:
+			if ev.TargetInfo.Type == "page" {
+				// Attaching issues CDP commands, which must not run on the listener goroutine
+				go c.attachPageTarget(conn, ev.TargetInfo.TargetID)
+			}
+		case *target.EventTargetDestroyed:
+			c.detachPageTarget(ev.TargetID)
+		}
+	})
+
+	browser := chromedp.FromContext(conn.ctx).Browser
+	if err := target.SetDiscoverTargets(true).Do(cdp.WithExecutor(conn.ctx, browser)); err != nil {
+		return err
+	}
+
+	c.runPageHooks(conn.ctx, conn.pageTargetID)
+	return nil
+}
+
+// attachPageTarget opens a chromedp context on a new page of conn's browser and runs the hooks against it
+func (c *Controller) attachPageTarget(conn *cdpConnection, targetID target.ID) {
+	c.targetsMu.Lock()
+	if _, ok := c.pageTargets[targetID]; ok {
+		c.targetsMu.Unlock()
+		return
+	}
+	ctx, cancel := chromedp.NewContext(conn.ctx, chromedp.WithTargetID(targetID))
+	c.pageTargets[targetID] = cancel
+	c.targetsMu.Unlock()
+
+	if err := chromedp.Run(ctx); err != nil {
+		log.Printf("Failed to attach to page target %s: %v", targetID, err)
+		c.detachPageTarget(targetID)
+		return
+	}
+
+	c.runPageHooks(ctx, targetID)
+}
+
+// detachPageTarget releases the chromedp context of a page that has gone away
+func (c *Controller) detachPageTarget(targetID target.ID) {
+	c.targetsMu.Lock()
+	cancel, ok := c.pageTargets[targetID]
+	delete(c.pageTargets, targetID)
+	c.targetsMu.Unlock()
+
+	if ok && cancel != nil {
+		cancel()
+	}
+}
+
+func (c *Controller) runPageHooks(ctx context.Context, targetID target.ID) {
+	for _, hook := range c.pageHooks {
+		hook(ctx, targetID)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/wallcrawler/backend-go/internal/cdpproxy"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// uploadsDir is where files uploaded through the API are placed for DOM.setFileInputFiles
+func (c *Controller) uploadsDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("uploads-%s", c.sessionID))
+}
+
+// fetchUpload pulls a file staged by the uploads API into the session's uploads
+// directory and removes the staged copy. It returns the file's path in the container.
+func (c *Controller) fetchUpload(ctx context.Context, req cdpproxy.UploadRequest) (string, error) {
+	if c.contextsBucket == "" {
+		return "", fmt.Errorf("upload storage not configured")
+	}
+
+	uploadID := utils.SanitizeFilename(req.UploadID)
+	filename := utils.SanitizeFilename(req.Filename)
+	key := utils.SessionUploadKey(c.projectID, c.sessionID, uploadID, filename)
+
+	dir := filepath.Join(c.uploadsDir(), uploadID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, filename)
+
+	object, err := c.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.contextsBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged upload: %v", err)
+	}
+	defer object.Body.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	size, err := io.Copy(file, object.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write upload: %v", err)
+	}
+
+	// The file now lives only in the container and goes away with it
+	if _, err := c.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(c.contextsBucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		log.Printf("Failed to delete staged upload %s for session %s: %v", key, c.sessionID, err)
+	}
+
+	log.Printf("Received upload %s (%d bytes) for session %s at %s", filename, size, c.sessionID, path)
+	return path, nil
+}
+
+// removeUploads deletes the session's uploaded files when the session ends
+func (c *Controller) removeUploads() {
+	if err := os.RemoveAll(c.uploadsDir()); err != nil {
+		log.Printf("Failed to remove uploads for session %s: %v", c.sessionID, err)
+	}
+}
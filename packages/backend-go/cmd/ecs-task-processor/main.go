@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
@@ -141,9 +144,9 @@ func handleECSTaskStateChange(ctx context.Context, event EventBridgeEvent) error
 		return nil
 	}
 
-	lastStatus, ok := event.Detail["lastStatus"].(string)
-	if !ok || lastStatus != "RUNNING" {
-		log.Printf("Task not in RUNNING state (%s), skipping", lastStatus)
+	lastStatus, _ := event.Detail["lastStatus"].(string)
+	if lastStatus != "RUNNING" && lastStatus != "DEPROVISIONING" && lastStatus != "STOPPED" {
+		log.Printf("Task in %s state, skipping", lastStatus)
 		return nil
 	}
 
@@ -154,6 +157,10 @@ func handleECSTaskStateChange(ctx context.Context, event EventBridgeEvent) error
 		return nil
 	}
 
+	if lastStatus != "RUNNING" {
+		return handleTaskStopped(ctx, event, taskArn, sessionID, lastStatus)
+	}
+
 	log.Printf("Processing ECS task RUNNING event for session %s, task %s", sessionID, taskArn)
 
 	// Get DynamoDB client
@@ -163,9 +170,8 @@ func handleECSTaskStateChange(ctx context.Context, event EventBridgeEvent) error
 		return err
 	}
 
-	// Get current session state from DynamoDB
-	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
-	if err != nil {
+	// Skip tasks whose session record is gone
+	if _, err := utils.GetSession(ctx, ddbClient, sessionID); err != nil {
 		log.Printf("Error getting session %s: %v", sessionID, err)
 		return nil
 	}
@@ -200,53 +206,137 @@ func handleECSTaskStateChange(ctx context.Context, event EventBridgeEvent) error
 
 	log.Printf("Successfully obtained task IP %s for session %s", taskIP, sessionID)
 
-	// Update status to READY in DynamoDB
-	if err := utils.UpdateSessionStatus(ctx, ddbClient, sessionID, types.SessionStatusReady); err != nil {
-		log.Printf("Error updating session status to READY: %v", err)
+	// Only the task's address is recorded here. The controller marks the session READY
+	// once the browser and CDP proxy are actually up.
+	if err := utils.SetSessionTaskAddress(ctx, ddbClient, sessionID, taskArn, taskIP); err != nil {
+		log.Printf("Error recording task address for session %s: %v", sessionID, err)
+		return err
 	}
 
-	// Refresh session state to capture latest lifecycle fields
-	refreshedState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	// Log ECS task running event
+	utils.LogECSTaskEvent(sessionID, taskArn, "RUNNING", map[string]interface{}{
+		"public_ip": taskIP,
+		"eni_id":    eniID,
+	})
+
+	log.Printf("Successfully processed ECS task state change for session %s with IP %s", sessionID, taskIP)
+	return nil
+}
+
+// handleTaskStopped finalizes the session of a task that is stopping. DEPROVISIONING is
+// handled like STOPPED so sessions end as soon as ECS reports the stop; the later STOPPED
+// event for the same task is then skipped.
+func handleTaskStopped(ctx context.Context, event EventBridgeEvent, taskArn, sessionID, lastStatus string) error {
+	log.Printf("Processing ECS task %s event for session %s, task %s", lastStatus, sessionID, taskArn)
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
 	if err != nil {
-		log.Printf("Error refreshing session state after status update: %v", err)
-	} else {
-		sessionState = refreshedState
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return err
 	}
 
-	// Update session with task information and connection endpoints
-	sessionState.PublicIP = taskIP
-	sessionState.ECSTaskARN = taskArn
-	sessionState.UpdatedAt = time.Now().Format(time.RFC3339)
-	sessionState.InternalStatus = types.SessionStatusReady
-	sessionState.Status = utils.MapStatusToSDK(types.SessionStatusReady)
-
-	// Generate connect URL if we have a signing key
-	if sessionState.SigningKey != nil && *sessionState.SigningKey != "" {
-		connectURL := utils.CreateAuthenticatedCDPURL(taskIP, *sessionState.SigningKey)
-		sessionState.ConnectURL = &connectURL
-		log.Printf("Updated session %s with IP %s and connect URL", sessionID, taskIP)
-	} else {
-		log.Printf("No signing key available for session %s", sessionID)
+	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	if err != nil {
+		log.Printf("Error getting session %s: %v", sessionID, err)
+		return nil
 	}
 
-	// Persist connection details
-	if err := utils.StoreSession(ctx, ddbClient, sessionState); err != nil {
-		log.Printf("Error storing updated session: %v", err)
+	stopCode, _ := event.Detail["stopCode"].(string)
+	stoppedReason, _ := event.Detail["stoppedReason"].(string)
+	status, reason := taskStopOutcome(event.Detail)
+
+	stoppedAt := time.Now()
+	if value, ok := event.Detail["stoppedAt"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			stoppedAt = parsed
+		}
+	}
+
+	finalStatus, err := utils.RecordSessionTaskStopped(ctx, ddbClient, sessionID, status, reason, stoppedAt)
+	if errors.Is(err, utils.ErrTaskStopAlreadyRecorded) {
+		log.Printf("Stop of task %s already recorded for session %s, skipping", taskArn, sessionID)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error finalizing session %s: %v", sessionID, err)
 		return err
 	}
 
-	// Session is now ready - DynamoDB update will trigger stream processor
+	detail := map[string]interface{}{
+		"sessionId":     sessionID,
+		"taskArn":       taskArn,
+		"lastStatus":    lastStatus,
+		"stopCode":      stopCode,
+		"stoppedReason": stoppedReason,
+		"status":        finalStatus,
+		"reason":        reason,
+	}
+	if err := utils.AddSessionEvent(ctx, ddbClient, sessionID, "SessionTaskStopped", "wallcrawler.ecs-task-processor", detail); err != nil {
+		log.Printf("Error recording task stop event for session %s: %v", sessionID, err)
+	}
 
-	// Log ECS task ready event
-	utils.LogECSTaskEvent(sessionID, taskArn, "RUNNING", map[string]interface{}{
-		"public_ip": taskIP,
-		"eni_id":    eniID,
+	var durationMs int64
+	if createdAt, err := time.Parse(time.RFC3339, sessionState.CreatedAt); err == nil {
+		durationMs = stoppedAt.Sub(createdAt).Milliseconds()
+	}
+	utils.LogSessionTerminated(sessionID, sessionState.ProjectID, reason, durationMs, map[string]interface{}{
+		"status":    finalStatus,
+		"stop_code": stopCode,
+		"task_arn":  taskArn,
 	})
 
-	log.Printf("Successfully processed ECS task state change for session %s with IP %s", sessionID, taskIP)
+	log.Printf("Session %s ended as %s after task %s stopped: %s", sessionID, finalStatus, taskArn, reason)
 	return nil
 }
 
+// taskStopOutcome maps a stopped task to the session's final status and a reason, from the
+// event's stopCode, stoppedReason and the containers' exit codes
+func taskStopOutcome(detail map[string]interface{}) (string, string) {
+	stopCode, _ := detail["stopCode"].(string)
+	stoppedReason, _ := detail["stoppedReason"].(string)
+
+	// The first container that exited abnormally explains a crash
+	exitCode := 0
+	containerReason := ""
+	if containers, ok := detail["containers"].([]interface{}); ok {
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			code, _ := container["exitCode"].(float64)
+			reason, _ := container["reason"].(string)
+			if code != 0 || strings.Contains(reason, "OutOfMemory") {
+				exitCode = int(code)
+				containerReason = reason
+				break
+			}
+		}
+	}
+
+	switch {
+	case strings.Contains(stoppedReason, utils.TaskStopReasonTimedOut):
+		return types.SessionStatusTimedOut, stoppedReason
+	case stopCode == "TaskFailedToStart":
+		return types.SessionStatusFailed, fmt.Sprintf("Task failed to start: %s", stoppedReason)
+	case strings.Contains(containerReason, "OutOfMemory"):
+		return types.SessionStatusFailed, fmt.Sprintf("Container ran out of memory: %s", containerReason)
+	case stopCode == "EssentialContainerExited" && exitCode != 0:
+		if containerReason != "" {
+			return types.SessionStatusFailed, fmt.Sprintf("Container exited with code %d: %s", exitCode, containerReason)
+		}
+		return types.SessionStatusFailed, fmt.Sprintf("Container exited with code %d", exitCode)
+	case stopCode == "SpotInterruption", stopCode == "TerminationNotice", stopCode == "ServiceSchedulerInitiated":
+		return types.SessionStatusFailed, fmt.Sprintf("Task stopped by ECS (%s): %s", stopCode, stoppedReason)
+	default:
+		// Released through the API, or the controller shut down on its own
+		if stoppedReason == "" {
+			stoppedReason = stopCode
+		}
+		return types.SessionStatusStopped, stoppedReason
+	}
+}
+
 // handleSessionTerminated processes manual session termination events
 func handleSessionTerminated(ctx context.Context, event EventBridgeEvent) error {
 	log.Printf("Processing SessionTerminated event")
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+func TestTaskStopOutcome(t *testing.T) {
+	tests := []struct {
+		name       string
+		detail     string // ECS Task State Change detail, as EventBridge delivers it
+		wantStatus string
+		wantReason string
+	}{
+		{
+			name:       "released through the API",
+			detail:     `{"stopCode":"UserInitiated","stoppedReason":"Session released by user","containers":[{"exitCode":0}]}`,
+			wantStatus: types.SessionStatusStopped,
+			wantReason: "Session released by user",
+		},
+		{
+			name:       "stop code without a reason",
+			detail:     `{"stopCode":"UserInitiated"}`,
+			wantStatus: types.SessionStatusStopped,
+			wantReason: "UserInitiated",
+		},
+		{
+			name:       "session timeout",
+			detail:     `{"stopCode":"UserInitiated","stoppedReason":"Session timed out after 300s"}`,
+			wantStatus: types.SessionStatusTimedOut,
+			wantReason: "Session timed out after 300s",
+		},
+		{
+			name:       "task failed to start",
+			detail:     `{"stopCode":"TaskFailedToStart","stoppedReason":"CannotPullContainerError"}`,
+			wantStatus: types.SessionStatusFailed,
+			wantReason: "Task failed to start: CannotPullContainerError",
+		},
+		{
+			name:       "out of memory",
+			detail:     `{"stopCode":"EssentialContainerExited","containers":[{"exitCode":137,"reason":"OutOfMemoryError: Container killed due to memory usage"}]}`,
+			wantStatus: types.SessionStatusFailed,
+			wantReason: "Container ran out of memory: OutOfMemoryError: Container killed due to memory usage",
+		},
+		{
+			name:       "crash with a reason",
+			detail:     `{"stopCode":"EssentialContainerExited","containers":[{"exitCode":0},{"exitCode":2,"reason":"panic"}]}`,
+			wantStatus: types.SessionStatusFailed,
+			wantReason: "Container exited with code 2: panic",
+		},
+		{
+			name:       "crash without a reason",
+			detail:     `{"stopCode":"EssentialContainerExited","containers":[{"exitCode":1}]}`,
+			wantStatus: types.SessionStatusFailed,
+			wantReason: "Container exited with code 1",
+		},
+		{
+			name:       "essential container exited cleanly",
+			detail:     `{"stopCode":"EssentialContainerExited","stoppedReason":"Essential container in task exited","containers":[{"exitCode":0}]}`,
+			wantStatus: types.SessionStatusStopped,
+			wantReason: "Essential container in task exited",
+		},
+		{
+			name:       "spot interruption",
+			detail:     `{"stopCode":"SpotInterruption","stoppedReason":"Your Spot Task was interrupted."}`,
+			wantStatus: types.SessionStatusFailed,
+			wantReason: "Task stopped by ECS (SpotInterruption): Your Spot Task was interrupted.",
+		},
+		{
+			name:       "malformed containers",
+			detail:     `{"stopCode":"EssentialContainerExited","stoppedReason":"exited","containers":["bad",{"exitCode":"1"}]}`,
+			wantStatus: types.SessionStatusStopped,
+			wantReason: "exited",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var detail map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.detail), &detail); err != nil {
+				t.Fatalf("decode detail: %v", err)
+			}
+
+			status, reason := taskStopOutcome(detail)
+			if status != tt.wantStatus || reason != tt.wantReason {
+				t.Errorf("taskStopOutcome() = (%q, %q), want (%q, %q)", status, reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"strings"
 	"time"
 
@@ -13,8 +14,10 @@ import (
 )
 
 type projectUsageResponse struct {
-	BrowserMinutes int `json:"browserMinutes"`
-	ProxyBytes     int `json:"proxyBytes"`
+	BrowserMinutes int     `json:"browserMinutes"`
+	ProxyBytes     int     `json:"proxyBytes"`
+	CPUSeconds     float64 `json:"cpuSeconds"`
+	MemoryMBHours  float64 `json:"memoryMBHours"`
 }
 
 func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -54,6 +57,7 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	var totalDuration time.Duration
 	var totalProxyBytes int
+	var totalCPUSeconds, totalMemoryMBHours float64
 
 	now := time.Now()
 	for _, session := range sessions {
@@ -80,11 +84,19 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		}
 
 		totalProxyBytes += session.ProxyBytes
+
+		// Sampled by the session's controller while the browser runs
+		if session.BillingInfo != nil {
+			totalCPUSeconds += session.BillingInfo.CPUSeconds
+			totalMemoryMBHours += session.BillingInfo.MemoryMBHours
+		}
 	}
 
 	usage := projectUsageResponse{
 		BrowserMinutes: int(totalDuration / time.Minute),
 		ProxyBytes:     totalProxyBytes,
+		CPUSeconds:     math.Round(totalCPUSeconds*1000) / 1000,
+		MemoryMBHours:  math.Round(totalMemoryMBHours*1000) / 1000,
 	}
 
 	return utils.CreateAPIResponse(200, utils.SuccessResponse(usage))
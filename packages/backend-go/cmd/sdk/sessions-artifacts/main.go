@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const artifactURLExpiry = 15 * time.Minute
+
+type sessionArtifact struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"createdAt"`
+	DownloadURL string `json:"downloadUrl"`
+	ExpiresAt   string `json:"expiresAt"`
+}
+
+type sessionArtifactsResponse struct {
+	SessionID string            `json:"sessionId"`
+	Artifacts []sessionArtifact `json:"artifacts"`
+}
+
+// Handler processes GET /v1/sessions/{id}/artifacts (files the controller uploaded for the session,
+// such as the screencast timelapse and the CDP traffic log)
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	sessionID := request.PathParameters["id"]
+	if sessionID == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Missing session ID parameter"))
+	}
+
+	projectID := utils.GetAuthorizedProjectID(request.RequestContext.Authorizer)
+	if projectID == "" {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Unauthorized project access"))
+	}
+
+	if utils.ContextsBucketName == "" {
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Artifact storage not configured"))
+	}
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
+	if err != nil {
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	// Session records expire with the session's TTL while its artifacts stay in S3.
+	// Without a record, the listing is limited to the caller's own project prefix.
+	sessionFound := false
+	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	if err == nil {
+		if !strings.EqualFold(sessionState.ProjectID, projectID) {
+			return utils.CreateAPIResponse(403, utils.ErrorResponse("Session does not belong to this project"))
+		}
+		projectID = sessionState.ProjectID
+		sessionFound = true
+	}
+
+	artifacts, err := listSessionArtifacts(ctx, projectID, sessionID)
+	if err != nil {
+		log.Printf("Error listing artifacts for session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to retrieve artifacts"))
+	}
+
+	if !sessionFound && len(artifacts) == 0 {
+		return utils.CreateAPIResponse(404, utils.ErrorResponse("Session not found"))
+	}
+
+	response := sessionArtifactsResponse{
+		SessionID: sessionID,
+		Artifacts: artifacts,
+	}
+
+	return utils.CreateAPIResponse(200, utils.SuccessResponse(response))
+}
+
+// listSessionArtifacts returns the objects directly under the session's artifact prefix.
+// Downloads, uploads and recording chunks are in subfolders and have their own endpoints.
+func listSessionArtifacts(ctx context.Context, projectID, sessionID string) ([]sessionArtifact, error) {
+	s3Client, err := utils.GetS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := utils.SessionArtifactsPrefix(projectID, sessionID)
+	expiresAt := time.Now().Add(artifactURLExpiry).UTC().Format(time.RFC3339)
+	artifacts := []sessionArtifact{}
+
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(utils.ContextsBucketName),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+
+			downloadURL, err := utils.GenerateDownloadURL(ctx, utils.ContextsBucketName, key, artifactURLExpiry)
+			if err != nil {
+				return nil, err
+			}
+
+			artifact := sessionArtifact{
+				Name:        strings.TrimPrefix(key, prefix),
+				Size:        aws.ToInt64(object.Size),
+				DownloadURL: downloadURL,
+				ExpiresAt:   expiresAt,
+			}
+			if object.LastModified != nil {
+				artifact.CreatedAt = object.LastModified.UTC().Format(time.RFC3339)
+			}
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].Name < artifacts[j].Name
+	})
+	return artifacts, nil
+}
+
+func main() {
+	lambda.Start(func(ctx context.Context, event interface{}) (interface{}, error) {
+		parsedEvent, eventType, err := utils.ParseLambdaEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if eventType != utils.EventTypeAPIGateway {
+			return nil, fmt.Errorf("expected API Gateway event, got %v", eventType)
+		}
+
+		apiReq := parsedEvent.(events.APIGatewayProxyRequest)
+		return Handler(ctx, apiReq)
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const recordingURLExpiry = 15 * time.Minute
+
+type cdpRecordingResponse struct {
+	SessionID   string `json:"sessionId"`
+	DownloadURL string `json:"downloadUrl"`
+	ExpiresAt   string `json:"expiresAt"`
+	Size        int64  `json:"size"`
+}
+
+// Handler processes GET /v1/sessions/{id}/cdp-recording (presigned CDP traffic log download)
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	sessionID := request.PathParameters["id"]
+	if sessionID == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Missing session ID parameter"))
+	}
+
+	projectID := utils.GetAuthorizedProjectID(request.RequestContext.Authorizer)
+	if projectID == "" {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Unauthorized project access"))
+	}
+
+	if utils.ContextsBucketName == "" {
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Recording storage not configured"))
+	}
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
+	if err != nil {
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	if err != nil {
+		log.Printf("Error getting session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(404, utils.ErrorResponse("Session not found"))
+	}
+
+	if !strings.EqualFold(sessionState.ProjectID, projectID) {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Session does not belong to this project"))
+	}
+
+	if !sessionState.RecordCDP {
+		return utils.CreateAPIResponse(404, utils.ErrorResponse("CDP recording was not enabled for this session"))
+	}
+
+	key := utils.CDPRecordingS3Key(sessionState.ProjectID, sessionID)
+
+	s3Client, err := utils.GetS3Client(ctx)
+	if err != nil {
+		log.Printf("Error getting S3 client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	// The log is only uploaded when the controller shuts down
+	head, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(utils.ContextsBucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *s3types.NotFound
+		if errors.As(err, &notFound) {
+			return utils.CreateAPIResponse(404, utils.ErrorResponse("CDP recording not available yet; it is uploaded when the session ends"))
+		}
+		log.Printf("Error checking CDP recording for session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to retrieve CDP recording"))
+	}
+
+	downloadURL, err := utils.GenerateDownloadURL(ctx, utils.ContextsBucketName, key, recordingURLExpiry)
+	if err != nil {
+		log.Printf("Error generating download URL for session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to generate download URL"))
+	}
+
+	response := cdpRecordingResponse{
+		SessionID:   sessionID,
+		DownloadURL: downloadURL,
+		ExpiresAt:   time.Now().Add(recordingURLExpiry).UTC().Format(time.RFC3339),
+		Size:        aws.ToInt64(head.ContentLength),
+	}
+
+	return utils.CreateAPIResponse(200, utils.SuccessResponse(response))
+}
+
+func main() {
+	lambda.Start(func(ctx context.Context, event interface{}) (interface{}, error) {
+		parsedEvent, eventType, err := utils.ParseLambdaEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if eventType != utils.EventTypeAPIGateway {
+			return nil, fmt.Errorf("expected API Gateway event, got %v", eventType)
+		}
+
+		apiReq := parsedEvent.(events.APIGatewayProxyRequest)
+		return Handler(ctx, apiReq)
+	})
+}
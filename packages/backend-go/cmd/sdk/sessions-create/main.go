@@ -3,10 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -25,29 +25,30 @@ type SessionCreateRequest struct {
 	Region          string                 `json:"region,omitempty"`
 	Timeout         int                    `json:"timeout,omitempty"`
 	UserMetadata    map[string]interface{} `json:"userMetadata,omitempty"`
+
+	// WaitForReady defaults to true. When false, the session is returned while still
+	// provisioning and the client polls GET /v1/sessions/{id} until it is ready.
+	WaitForReady *bool `json:"waitForReady,omitempty"`
 }
 
+// readyTimeout bounds how long a synchronous create waits for the container.
+// 45 seconds should handle most cold starts and network delays.
+const readyTimeout = 45 * time.Second
+
 type browserSettingsContext struct {
 	ID      string `json:"id"`
 	Persist bool   `json:"persist"`
 }
 
 type browserSettings struct {
-	Context *browserSettingsContext `json:"context,omitempty"`
-}
-
-// SessionReadyNotification represents the message from SNS
-type SessionReadyNotification struct {
-	SessionID         string `json:"sessionId"`
-	ProjectID         string `json:"projectId"`
-	Status            string `json:"status"`
-	ConnectURL        string `json:"connectUrl"`
-	SeleniumRemoteURL string `json:"seleniumRemoteUrl"`
-	PublicIP          string `json:"publicIp"`
-	CreatedAt         string `json:"createdAt"`
-	ExpiresAt         string `json:"expiresAt"`
-	Region            string `json:"region"`
-	KeepAlive         bool   `json:"keepAlive"`
+	types.BrowserSettings
+	Context       *browserSettingsContext `json:"context,omitempty"`
+	RecordCDP     bool                    `json:"recordCdp,omitempty"`
+	RecordSession bool                    `json:"recordSession,omitempty"` // rrweb-style DOM recording
+
+	// Screencast frames assembled into a timelapse, at most ScreencastFPS frames per second
+	RecordScreencast bool    `json:"recordScreencast,omitempty"`
+	ScreencastFPS    float64 `json:"screencastFps,omitempty"`
 }
 
 // SessionCreateResponse represents the response to the client
@@ -65,13 +66,8 @@ type SessionCreateResponse struct {
 	SigningKey        string `json:"signingKey"`
 }
 
-// Global variables for session ready notifications
-var (
-	sessionReadyChannels sync.Map // map[sessionID]chan SessionReadyNotification
-)
-
 // Handler processes session creation requests from API Gateway
-// This function creates the ECS task and waits synchronously for it to be ready
+// This function creates the ECS task and, unless waitForReady is false, waits for it to be ready
 func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Parse request body
 	var req SessionCreateRequest
@@ -103,10 +99,25 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	var parsedSettings browserSettings
 	if req.BrowserSettings != nil {
 		if raw, err := json.Marshal(req.BrowserSettings); err == nil {
-			_ = json.Unmarshal(raw, &parsedSettings)
+			if err := json.Unmarshal(raw, &parsedSettings); err != nil {
+				return utils.CreateAPIResponse(400, utils.ErrorResponse(fmt.Sprintf("Invalid browserSettings: %v", err)))
+			}
 		}
 	}
 
+	if err := utils.ValidateBrowserSettings(&parsedSettings.BrowserSettings); err != nil {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse(fmt.Sprintf("Invalid browserSettings: %v", err)))
+	}
+
+	if err := utils.ValidateScreencastFPS(parsedSettings.ScreencastFPS); err != nil {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse(fmt.Sprintf("Invalid browserSettings: %v", err)))
+	}
+
+	proxies, err := utils.ParseSessionProxies(req.Proxies)
+	if err != nil {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse(fmt.Sprintf("Invalid proxies: %v", err)))
+	}
+
 	// Generate session ID
 	sessionID := utils.GenerateSessionID()
 
@@ -129,6 +140,15 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	// Update fields from request
 	sessionState.KeepAlive = req.KeepAlive
 	sessionState.Region = region
+	sessionState.RecordCDP = parsedSettings.RecordCDP
+	sessionState.RecordSession = parsedSettings.RecordSession
+	sessionState.RecordScreencast = parsedSettings.RecordScreencast
+	sessionState.ScreencastFPS = parsedSettings.ScreencastFPS
+	if !utils.IsEmptyBrowserSettings(&parsedSettings.BrowserSettings) {
+		settings := parsedSettings.BrowserSettings
+		sessionState.BrowserSettings = &settings
+	}
+	sessionState.Proxies = proxies
 
 	// Update expiration based on timeout
 	expiresAt := time.Now().Add(time.Duration(req.Timeout) * time.Second)
@@ -157,16 +177,14 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		sessionState.UserMetadata["contextPersist"] = contextPersist
 	}
 
-	// Create a channel to wait for session ready notification
-	readyChan := make(chan SessionReadyNotification, 1)
-	sessionReadyChannels.Store(sessionID, readyChan)
-	defer sessionReadyChannels.Delete(sessionID)
+	waitForReady := req.WaitForReady == nil || *req.WaitForReady
 
 	// Log session creation
 	utils.LogSessionCreated(sessionID, req.ProjectID, map[string]interface{}{
 		"timeout":       req.Timeout,
 		"user_metadata": req.UserMetadata,
-		"synchronous":   true,
+		"synchronous":   waitForReady,
+		"proxies":       len(proxies),
 	})
 
 	// Get DynamoDB client
@@ -207,6 +225,21 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		Nonce:     utils.GenerateRandomNonce(),
 	}
 
+	// Carry the project's CDP method policy in the token and the task environment so the
+	// proxy can enforce it, along with its action limit. A failed lookup only means the
+	// session runs without a policy and with the default action limit.
+	project, err := utils.GetProjectMetadata(ctx, ddbClient, req.ProjectID)
+	if err != nil {
+		log.Printf("Warning: could not load project %s metadata, creating session without a CDP policy and with the default action limit: %v", req.ProjectID, err)
+	} else {
+		payload.CDPPolicy = project.CDPPolicy
+		sessionState.CDPPolicy = project.CDPPolicy
+		// Projects override the default action limit, or opt out with a negative value
+		if project.MaxActions != 0 && sessionState.ResourceLimits != nil {
+			sessionState.ResourceLimits.MaxActions = max(project.MaxActions, 0)
+		}
+	}
+
 	jwtToken, err := utils.CreateCDPToken(payload)
 	if err != nil {
 		log.Printf("Error creating JWT token for session %s: %v", sessionID, err)
@@ -236,32 +269,60 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	taskARN, err := utils.CreateECSTask(ctx, sessionID, sessionState)
 	if err != nil {
 		log.Printf("Error creating ECS task for session %s: %v", sessionID, err)
-		utils.UpdateSessionStatus(ctx, ddbClient, sessionID, types.SessionStatusFailed)
+		if markErr := utils.MarkSessionFailed(ctx, ddbClient, sessionID, fmt.Sprintf("Failed to launch browser task: %v", err)); markErr != nil {
+			log.Printf("Error marking session %s as failed: %v", sessionID, markErr)
+		}
 		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to provision browser container"))
 	}
 
-	// Update session with task ARN
+	// Only the task ARN is written, since the container may already be updating the record
 	sessionState.ECSTaskARN = taskARN
-	if err := utils.StoreSession(ctx, ddbClient, sessionState); err != nil {
+	if err := utils.SetSessionTaskARN(ctx, ddbClient, sessionID, taskARN); err != nil {
 		log.Printf("Error storing session with task ARN: %v", err)
 	}
 
+	if !waitForReady {
+		log.Printf("Successfully initiated ECS task %s for session %s, returning before the container is ready", taskARN, sessionID)
+
+		response := SessionCreateResponse{
+			ID:         sessionID,
+			Status:     utils.MapStatusToSDK(sessionState.InternalStatus),
+			CreatedAt:  sessionState.CreatedAt,
+			ExpiresAt:  sessionState.ExpiresAt,
+			ProjectID:  req.ProjectID,
+			KeepAlive:  req.KeepAlive,
+			Region:     region,
+			SigningKey: jwtToken,
+		}
+
+		return utils.CreateAPIResponse(200, response)
+	}
+
 	log.Printf("Successfully initiated ECS task %s for session %s, waiting for container to be ready", taskARN, sessionID)
 
-	// Wait for session to be ready with timeout
-	// 45 seconds should handle most cold starts and network delays
-	timeout := time.Duration(45) * time.Second
-	select {
-	case notification := <-readyChan:
-		// Session is ready, return the complete details
-		log.Printf("Session %s is ready with connect URL: %s", sessionID, notification.ConnectURL)
+	// Readiness is read from the session record, so it does not matter which Lambda
+	// instance processed the task's state change
+	waitCtx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	readyState, err := utils.WaitForSessionReady(waitCtx, ddbClient, sessionID)
+	switch {
+	case err == nil:
+		connectURL, seleniumRemoteURL := "", ""
+		if readyState.ConnectURL != nil {
+			connectURL = *readyState.ConnectURL
+		}
+		if readyState.SeleniumRemoteURL != nil {
+			seleniumRemoteURL = *readyState.SeleniumRemoteURL
+		}
+		log.Printf("Session %s is ready with connect URL: %s", sessionID, connectURL)
 
 		response := SessionCreateResponse{
 			ID:                sessionID,
 			Status:            "RUNNING",
-			ConnectURL:        notification.ConnectURL,
-			PublicIP:          notification.PublicIP,
-			SeleniumRemoteURL: notification.SeleniumRemoteURL,
+			ConnectURL:        connectURL,
+			PublicIP:          readyState.PublicIP,
+			SeleniumRemoteURL: seleniumRemoteURL,
 			CreatedAt:         sessionState.CreatedAt,
 			ExpiresAt:         sessionState.ExpiresAt,
 			ProjectID:         req.ProjectID,
@@ -272,43 +333,28 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 		return utils.CreateAPIResponse(200, response)
 
-	case <-time.After(timeout):
-		// Timeout waiting for session to be ready
-		log.Printf("Timeout waiting for session %s to be ready", sessionID)
-		utils.StopECSTask(ctx, taskARN)
-		utils.UpdateSessionStatus(ctx, ddbClient, sessionID, types.SessionStatusTimedOut)
-		return utils.CreateAPIResponse(504, utils.ErrorResponse("Timeout waiting for browser container to be ready"))
-	}
-}
-
-// SNSHandler processes SNS messages for session ready notifications
-func SNSHandler(ctx context.Context, snsEvent events.SNSEvent) error {
-	for _, record := range snsEvent.Records {
-		// Parse the notification
-		var notification SessionReadyNotification
-		if err := json.Unmarshal([]byte(record.SNS.Message), &notification); err != nil {
-			log.Printf("Error unmarshaling SNS message: %v", err)
-			continue
+	case errors.Is(err, utils.ErrSessionStartFailed):
+		reason := ""
+		if readyState.FailureReason != nil {
+			reason = *readyState.FailureReason
 		}
+		log.Printf("Session %s failed to start (status %s): %s", sessionID, readyState.InternalStatus, reason)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Browser container failed to start"))
 
-		// Check if we have a channel waiting for this session
-		if ch, ok := sessionReadyChannels.Load(notification.SessionID); ok {
-			if readyChan, ok := ch.(chan SessionReadyNotification); ok {
-				// Send notification to waiting channel (non-blocking)
-				select {
-				case readyChan <- notification:
-					log.Printf("Delivered ready notification for session %s", notification.SessionID)
-				default:
-					log.Printf("Channel full or closed for session %s", notification.SessionID)
-				}
-			}
+	default:
+		// Timeout waiting for session to be ready
+		log.Printf("Timeout waiting for session %s to be ready: %v", sessionID, err)
+		utils.StopECSTaskWithReason(ctx, taskARN, utils.TaskStopReasonTimedOut)
+		if err := utils.UpdateSessionStatus(ctx, ddbClient, sessionID, types.SessionStatusTimedOut); errors.Is(err, utils.ErrInvalidSessionTransition) {
+			log.Printf("Session %s ended before it could be timed out: %v", sessionID, err)
+		} else if err != nil {
+			log.Printf("Error timing out session %s: %v", sessionID, err)
 		}
+		return utils.CreateAPIResponse(504, utils.ErrorResponse("Timeout waiting for browser container to be ready"))
 	}
-	return nil
 }
 
 func main() {
-	// This Lambda handles both API Gateway requests and SNS notifications
 	lambda.Start(func(ctx context.Context, event interface{}) (interface{}, error) {
 		// Parse the event using the utility function
 		parsedEvent, eventType, err := utils.ParseLambdaEvent(event)
@@ -316,15 +362,11 @@ func main() {
 			return nil, err
 		}
 
-		switch eventType {
-		case utils.EventTypeAPIGateway:
-			apiReq := parsedEvent.(events.APIGatewayProxyRequest)
-			return Handler(ctx, apiReq)
-		case utils.EventTypeSNS:
-			snsEvent := parsedEvent.(events.SNSEvent)
-			return nil, SNSHandler(ctx, snsEvent)
-		default:
-			return nil, fmt.Errorf("unexpected event type")
+		if eventType != utils.EventTypeAPIGateway {
+			return nil, fmt.Errorf("expected API Gateway event, got %v", eventType)
 		}
+
+		apiReq := parsedEvent.(events.APIGatewayProxyRequest)
+		return Handler(ctx, apiReq)
 	})
 }
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -16,6 +19,7 @@ type SessionLiveURLsResponse struct {
 	DebuggerFullscreenURL string                `json:"debuggerFullscreenUrl"`
 	DebuggerURL           string                `json:"debuggerUrl"`
 	WsURL                 string                `json:"wsUrl"`
+	TakeoverURL           string                `json:"takeoverUrl,omitempty"`
 	Pages                 []SessionLiveURLsPage `json:"pages"`
 }
 
@@ -28,6 +32,52 @@ type SessionLiveURLsPage struct {
 	URL                   string `json:"url"`
 }
 
+// controllerTimeout bounds how long we wait for the controller to list its pages
+const controllerTimeout = 3 * time.Second
+
+// controllerTarget is a target entry from the controller's /json listing
+type controllerTarget struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	FaviconURL string `json:"faviconUrl"`
+}
+
+// fetchPages asks the session's controller, through the authenticated proxy, for its open pages
+func fetchPages(ctx context.Context, taskIP, jwtToken string) ([]controllerTarget, error) {
+	ctx, cancel := context.WithTimeout(ctx, controllerTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, utils.CreateControllerURL(taskIP, "/json", jwtToken), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("controller returned status %d", resp.StatusCode)
+	}
+
+	var targets []controllerTarget
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("failed to decode controller targets: %v", err)
+	}
+
+	pages := make([]controllerTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.Type == "page" {
+			pages = append(pages, target)
+		}
+	}
+	return pages, nil
+}
+
 // Handler processes GET /v1/sessions/{id}/debug (SDK-compatible debug/live URLs)
 func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Extract session ID from path parameters
@@ -60,7 +110,7 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		return utils.CreateAPIResponse(403, utils.ErrorResponse("Session does not belong to this project"))
 	}
 
-	if !utils.IsSessionActive(sessionState.Status) {
+	if !utils.IsSessionActive(sessionState.InternalStatus) {
 		return utils.CreateAPIResponse(400, utils.ErrorResponse("Session is not active"))
 	}
 
@@ -74,7 +124,7 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	}
 	jwtToken := *sessionState.SigningKey
 
-	// Create debug URLs using utility functions for consistency
+	// Live view URLs served by the controller; they follow the active tab
 	debuggerURL := utils.CreateDebuggerURL(sessionState.PublicIP, jwtToken)
 	debuggerFullscreenURL := utils.CreateDebuggerFullscreenURL(sessionState.PublicIP, jwtToken)
 
@@ -84,24 +134,32 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		responseWSURL = *sessionState.ConnectURL
 	}
 
-	// Create response with proper debug URLs
+	// List the real pages; if the controller is unreachable the session-level URLs still work
+	pages := []SessionLiveURLsPage{}
+	targets, err := fetchPages(ctx, sessionState.PublicIP, jwtToken)
+	if err != nil {
+		log.Printf("Could not list pages for session %s: %v", sessionID, err)
+	}
+	for _, target := range targets {
+		pages = append(pages, SessionLiveURLsPage{
+			ID:                    target.ID,
+			DebuggerFullscreenURL: utils.CreatePageLiveViewURL(sessionState.PublicIP, jwtToken, target.ID, true),
+			DebuggerURL:           utils.CreatePageDebuggerURL(sessionState.PublicIP, jwtToken, target.ID),
+			FaviconURL:            target.FaviconURL,
+			Title:                 target.Title,
+			URL:                   target.URL,
+		})
+	}
+
 	response := SessionLiveURLsResponse{
 		DebuggerFullscreenURL: debuggerFullscreenURL,
 		DebuggerURL:           debuggerURL,
 		WsURL:                 responseWSURL,
-		Pages: []SessionLiveURLsPage{
-			{
-				ID:                    fmt.Sprintf("page_%s", sessionState.ID),
-				DebuggerFullscreenURL: debuggerFullscreenURL,
-				DebuggerURL:           debuggerURL,
-				FaviconURL:            "",
-				Title:                 "Browser Session",
-				URL:                   "about:blank",
-			},
-		},
-	}
-
-	log.Printf("Generated debug URLs for session %s with IP %s", sessionID, sessionState.PublicIP)
+		TakeoverURL:           utils.CreateTakeoverURL(sessionState.PublicIP, jwtToken),
+		Pages:                 pages,
+	}
+
+	log.Printf("Generated debug URLs for session %s with IP %s (%d pages)", sessionID, sessionState.PublicIP, len(pages))
 	return utils.CreateAPIResponse(200, utils.SuccessResponse(response))
 }
 
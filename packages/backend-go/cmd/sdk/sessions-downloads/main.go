@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const downloadURLExpiry = 15 * time.Minute
+
+type sessionDownload struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"createdAt"`
+	DownloadURL string `json:"downloadUrl"`
+	ExpiresAt   string `json:"expiresAt"`
+}
+
+type sessionDownloadsResponse struct {
+	SessionID string            `json:"sessionId"`
+	Downloads []sessionDownload `json:"downloads"`
+}
+
+// Handler processes GET /v1/sessions/{id}/downloads (files downloaded by the browser)
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	sessionID := request.PathParameters["id"]
+	if sessionID == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Missing session ID parameter"))
+	}
+
+	projectID := utils.GetAuthorizedProjectID(request.RequestContext.Authorizer)
+	if projectID == "" {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Unauthorized project access"))
+	}
+
+	if utils.ContextsBucketName == "" {
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Download storage not configured"))
+	}
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
+	if err != nil {
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	// Session records expire with the session's TTL while its downloads stay in S3.
+	// Without a record, the listing is limited to the caller's own project prefix.
+	sessionFound := false
+	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	if err == nil {
+		if !strings.EqualFold(sessionState.ProjectID, projectID) {
+			return utils.CreateAPIResponse(403, utils.ErrorResponse("Session does not belong to this project"))
+		}
+		projectID = sessionState.ProjectID
+		sessionFound = true
+	}
+
+	downloads, err := listSessionDownloads(ctx, projectID, sessionID)
+	if err != nil {
+		log.Printf("Error listing downloads for session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to retrieve downloads"))
+	}
+
+	if !sessionFound && len(downloads) == 0 {
+		return utils.CreateAPIResponse(404, utils.ErrorResponse("Session not found"))
+	}
+
+	response := sessionDownloadsResponse{
+		SessionID: sessionID,
+		Downloads: downloads,
+	}
+
+	return utils.CreateAPIResponse(200, utils.SuccessResponse(response))
+}
+
+// listSessionDownloads returns the session's uploaded downloads, oldest first
+func listSessionDownloads(ctx context.Context, projectID, sessionID string) ([]sessionDownload, error) {
+	s3Client, err := utils.GetS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := utils.SessionDownloadsPrefix(projectID, sessionID)
+	expiresAt := time.Now().Add(downloadURLExpiry).UTC().Format(time.RFC3339)
+	downloads := []sessionDownload{}
+
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(utils.ContextsBucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+
+			// Keys are {prefix}{downloadId}/{filename}
+			downloadID, filename, ok := strings.Cut(strings.TrimPrefix(key, prefix), "/")
+			if !ok || filename == "" {
+				continue
+			}
+
+			downloadURL, err := utils.GenerateDownloadURL(ctx, utils.ContextsBucketName, key, downloadURLExpiry)
+			if err != nil {
+				return nil, err
+			}
+
+			download := sessionDownload{
+				ID:          downloadID,
+				Filename:    filename,
+				Size:        aws.ToInt64(object.Size),
+				DownloadURL: downloadURL,
+				ExpiresAt:   expiresAt,
+			}
+			if object.LastModified != nil {
+				download.CreatedAt = object.LastModified.UTC().Format(time.RFC3339)
+			}
+			downloads = append(downloads, download)
+		}
+	}
+
+	sort.SliceStable(downloads, func(i, j int) bool {
+		return downloads[i].CreatedAt < downloads[j].CreatedAt
+	})
+	return downloads, nil
+}
+
+func main() {
+	lambda.Start(func(ctx context.Context, event interface{}) (interface{}, error) {
+		parsedEvent, eventType, err := utils.ParseLambdaEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if eventType != utils.EventTypeAPIGateway {
+			return nil, fmt.Errorf("expected API Gateway event, got %v", eventType)
+		}
+
+		apiReq := parsedEvent.(events.APIGatewayProxyRequest)
+		return Handler(ctx, apiReq)
+	})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/wallcrawler/backend-go/internal/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const defaultLogPageSize = 100
+
+var (
+	validLogTypes = map[string]bool{
+		types.SessionLogTypeConsole:   true,
+		types.SessionLogTypeException: true,
+		types.SessionLogTypeBrowser:   true,
+		types.SessionLogTypeNetwork:   true,
+	}
+	validLogLevels = map[string]bool{
+		types.SessionLogLevelDebug:   true,
+		types.SessionLogLevelInfo:    true,
+		types.SessionLogLevelWarning: true,
+		types.SessionLogLevelError:   true,
+	}
+)
+
+type sessionLogsResponse struct {
+	SessionID  string                  `json:"sessionId"`
+	Logs       []types.SessionLogEntry `json:"logs"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
+
+// Handler processes GET /v1/sessions/{id}/logs (captured console, exception, browser and network logs)
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	sessionID := request.PathParameters["id"]
+	if sessionID == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Missing session ID parameter"))
+	}
+
+	projectID := utils.GetAuthorizedProjectID(request.RequestContext.Authorizer)
+	if projectID == "" {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Unauthorized project access"))
+	}
+
+	if utils.SessionLogsTableName == "" {
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Log storage not configured"))
+	}
+
+	query, err := parseLogQuery(request.QueryStringParameters)
+	if err != nil {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse(err.Error()))
+	}
+	query.SessionID = sessionID
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
+	if err != nil {
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	// Session records expire with the session's TTL while its logs are retained longer.
+	// Without a record, only entries written for the caller's project are returned.
+	sessionFound := false
+	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	if err == nil {
+		if !strings.EqualFold(sessionState.ProjectID, projectID) {
+			return utils.CreateAPIResponse(403, utils.ErrorResponse("Session does not belong to this project"))
+		}
+		sessionFound = true
+	} else {
+		query.ProjectID = projectID
+	}
+
+	entries, nextCursor, err := utils.QuerySessionLogs(ctx, ddbClient, query)
+	if errors.Is(err, utils.ErrInvalidLogCursor) {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Invalid cursor"))
+	}
+	if err != nil {
+		log.Printf("Error querying logs for session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to retrieve logs"))
+	}
+
+	if !sessionFound && len(entries) == 0 && query.Cursor == "" {
+		return utils.CreateAPIResponse(404, utils.ErrorResponse("Session not found"))
+	}
+
+	response := sessionLogsResponse{
+		SessionID:  sessionID,
+		Logs:       entries,
+		NextCursor: nextCursor,
+	}
+
+	return utils.CreateAPIResponse(200, utils.SuccessResponse(response))
+}
+
+// parseLogQuery reads the type, level, start, end, limit and cursor query parameters
+func parseLogQuery(params map[string]string) (utils.SessionLogQuery, error) {
+	query := utils.SessionLogQuery{
+		Limit:  defaultLogPageSize,
+		Cursor: params["cursor"],
+	}
+
+	var err error
+	if query.Types, err = parseList(params["type"], validLogTypes); err != nil {
+		return query, fmt.Errorf("Invalid type: %v", err)
+	}
+	if query.Levels, err = parseList(params["level"], validLogLevels); err != nil {
+		return query, fmt.Errorf("Invalid level: %v", err)
+	}
+	if query.Start, err = parseTime(params["start"]); err != nil {
+		return query, fmt.Errorf("Invalid start: %v", err)
+	}
+	if query.End, err = parseTime(params["end"]); err != nil {
+		return query, fmt.Errorf("Invalid end: %v", err)
+	}
+	if !query.Start.IsZero() && !query.End.IsZero() && query.End.Before(query.Start) {
+		return query, fmt.Errorf("end must not be before start")
+	}
+
+	if value := params["limit"]; value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > utils.MaxSessionLogPageSize {
+			return query, fmt.Errorf("limit must be between 1 and %d", utils.MaxSessionLogPageSize)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// parseList splits a comma-separated filter and checks each value
+func parseList(value string, valid map[string]bool) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if !valid[item] {
+			return nil, fmt.Errorf("unknown value %q", item)
+		}
+		values = append(values, item)
+	}
+	return values, nil
+}
+
+// parseTime accepts RFC3339 timestamps or Unix milliseconds
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 or Unix milliseconds")
+	}
+	return t, nil
+}
+
+func main() {
+	lambda.Start(func(ctx context.Context, event interface{}) (interface{}, error) {
+		parsedEvent, eventType, err := utils.ParseLambdaEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if eventType != utils.EventTypeAPIGateway {
+			return nil, fmt.Errorf("expected API Gateway event, got %v", eventType)
+		}
+
+		apiReq := parsedEvent.(events.APIGatewayProxyRequest)
+		return Handler(ctx, apiReq)
+	})
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// maxRecordingBytes keeps the response under the Lambda payload limit
+const maxRecordingBytes = 5 << 20
+
+var errRecordingTooLarge = errors.New("recording exceeds the response size limit")
+
+type recordingPage struct {
+	ID        string `json:"id"`
+	StartedAt string `json:"startedAt"`
+	chunks    []string
+}
+
+type sessionRecordingResponse struct {
+	SessionID string            `json:"sessionId"`
+	PageID    string            `json:"pageId"`
+	Pages     []recordingPage   `json:"pages"`
+	Events    []json.RawMessage `json:"events"` // rrweb events of the selected page, oldest first
+}
+
+// Handler processes GET /v1/sessions/{id}/recording (rrweb-compatible DOM recording)
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	sessionID := request.PathParameters["id"]
+	if sessionID == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Missing session ID parameter"))
+	}
+
+	projectID := utils.GetAuthorizedProjectID(request.RequestContext.Authorizer)
+	if projectID == "" {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Unauthorized project access"))
+	}
+
+	if utils.ContextsBucketName == "" {
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Recording storage not configured"))
+	}
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
+	if err != nil {
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	// Session records expire with the session's TTL while the recording stays in S3.
+	// Without a record, the lookup is limited to the caller's own project prefix.
+	sessionFound := false
+	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	if err == nil {
+		if !strings.EqualFold(sessionState.ProjectID, projectID) {
+			return utils.CreateAPIResponse(403, utils.ErrorResponse("Session does not belong to this project"))
+		}
+		if !sessionState.RecordSession {
+			return utils.CreateAPIResponse(404, utils.ErrorResponse("Session recording was not enabled for this session"))
+		}
+		projectID = sessionState.ProjectID
+		sessionFound = true
+	}
+
+	s3Client, err := utils.GetS3Client(ctx)
+	if err != nil {
+		log.Printf("Error getting S3 client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	pages, err := listRecordingPages(ctx, s3Client, projectID, sessionID)
+	if err != nil {
+		log.Printf("Error listing recording for session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to retrieve recording"))
+	}
+
+	if len(pages) == 0 {
+		if !sessionFound {
+			return utils.CreateAPIResponse(404, utils.ErrorResponse("Session not found"))
+		}
+		return utils.CreateAPIResponse(404, utils.ErrorResponse("Session recording not available yet"))
+	}
+
+	// The first page opened is returned unless another one is requested
+	selected := &pages[0]
+	if pageID := request.QueryStringParameters["pageId"]; pageID != "" {
+		selected = nil
+		for i := range pages {
+			if pages[i].ID == pageID {
+				selected = &pages[i]
+				break
+			}
+		}
+		if selected == nil {
+			return utils.CreateAPIResponse(404, utils.ErrorResponse("Page not found in this recording"))
+		}
+	}
+
+	recordedEvents, err := readRecordingEvents(ctx, s3Client, selected.chunks)
+	if errors.Is(err, errRecordingTooLarge) {
+		return utils.CreateAPIResponse(413, utils.ErrorResponse("Recording is too large to return in one response"))
+	}
+	if err != nil {
+		log.Printf("Error reading recording for session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to retrieve recording"))
+	}
+
+	response := sessionRecordingResponse{
+		SessionID: sessionID,
+		PageID:    selected.ID,
+		Pages:     pages,
+		Events:    recordedEvents,
+	}
+
+	return utils.CreateAPIResponse(200, utils.SuccessResponse(response))
+}
+
+// listRecordingPages groups the recording's chunk keys by page, in the order pages were opened
+func listRecordingPages(ctx context.Context, s3Client *s3.Client, projectID, sessionID string) ([]recordingPage, error) {
+	prefix := utils.SessionRecordingPrefix(projectID, sessionID)
+	byID := map[string]*recordingPage{}
+	var pages []*recordingPage
+	started := map[string]time.Time{}
+
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(utils.ContextsBucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range result.Contents {
+			key := aws.ToString(object.Key)
+
+			// Keys are {prefix}{pageId}/{seq}.json
+			pageID, chunk, ok := strings.Cut(strings.TrimPrefix(key, prefix), "/")
+			if !ok || !strings.HasSuffix(chunk, ".json") {
+				continue
+			}
+
+			p, ok := byID[pageID]
+			if !ok {
+				p = &recordingPage{ID: pageID}
+				byID[pageID] = p
+				pages = append(pages, p)
+			}
+			p.chunks = append(p.chunks, key)
+
+			if object.LastModified != nil {
+				if t, ok := started[pageID]; !ok || object.LastModified.Before(t) {
+					started[pageID] = *object.LastModified
+				}
+			}
+		}
+	}
+
+	result := make([]recordingPage, 0, len(pages))
+	for _, p := range pages {
+		sort.Strings(p.chunks)
+		if t, ok := started[p.ID]; ok {
+			p.StartedAt = t.UTC().Format(time.RFC3339)
+		}
+		result = append(result, *p)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return started[result[i].ID].Before(started[result[j].ID])
+	})
+	return result, nil
+}
+
+// readRecordingEvents concatenates a page's chunks and orders the events by timestamp
+func readRecordingEvents(ctx context.Context, s3Client *s3.Client, keys []string) ([]json.RawMessage, error) {
+	type timedEvent struct {
+		raw       json.RawMessage
+		timestamp int64
+	}
+
+	var timed []timedEvent
+	total := 0
+	for _, key := range keys {
+		object, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(utils.ContextsBucketName),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		total += int(aws.ToInt64(object.ContentLength))
+		if total > maxRecordingBytes {
+			object.Body.Close()
+			return nil, errRecordingTooLarge
+		}
+
+		var chunk []json.RawMessage
+		err = json.NewDecoder(object.Body).Decode(&chunk)
+		object.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("malformed recording chunk %s: %v", key, err)
+		}
+
+		for _, raw := range chunk {
+			var header struct {
+				Timestamp int64 `json:"timestamp"`
+			}
+			if err := json.Unmarshal(raw, &header); err != nil {
+				continue
+			}
+			timed = append(timed, timedEvent{raw: raw, timestamp: header.Timestamp})
+		}
+	}
+
+	// Chunks are in order, but sampled mouse moves are emitted after events that happened in between
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].timestamp < timed[j].timestamp
+	})
+
+	recordedEvents := make([]json.RawMessage, 0, len(timed))
+	for _, event := range timed {
+		recordedEvents = append(recordedEvents, event.raw)
+	}
+	return recordedEvents, nil
+}
+
+func main() {
+	lambda.Start(func(ctx context.Context, event interface{}) (interface{}, error) {
+		parsedEvent, eventType, err := utils.ParseLambdaEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if eventType != utils.EventTypeAPIGateway {
+			return nil, fmt.Errorf("expected API Gateway event, got %v", eventType)
+		}
+
+		apiReq := parsedEvent.(events.APIGatewayProxyRequest)
+		return Handler(ctx, apiReq)
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const (
+	defaultShareExpiry = 15 * time.Minute
+	maxShareExpiry     = 24 * time.Hour
+	maxShareViewers    = 100
+)
+
+// SessionShareRequest represents the share link request body
+type SessionShareRequest struct {
+	ExpiresIn  int `json:"expiresIn,omitempty"`  // Seconds until the link expires
+	MaxViewers int `json:"maxViewers,omitempty"` // Concurrent viewers allowed, 0 = unlimited
+}
+
+// SessionShareResponse contains the view-only live URLs
+type SessionShareResponse struct {
+	SessionID             string `json:"sessionId"`
+	Scope                 string `json:"scope"`
+	SigningKey            string `json:"signingKey"`
+	DebuggerURL           string `json:"debuggerUrl"`
+	DebuggerFullscreenURL string `json:"debuggerFullscreenUrl"`
+	ExpiresAt             string `json:"expiresAt"`
+	MaxViewers            int    `json:"maxViewers,omitempty"`
+}
+
+// Handler processes POST /v1/sessions/{id}/share (view-only live view links)
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	sessionID := request.PathParameters["id"]
+	if sessionID == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Missing session ID parameter"))
+	}
+
+	projectID := utils.GetAuthorizedProjectID(request.RequestContext.Authorizer)
+	if projectID == "" {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Unauthorized project access"))
+	}
+
+	var req SessionShareRequest
+	if strings.TrimSpace(request.Body) != "" {
+		if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+			return utils.CreateAPIResponse(400, utils.ErrorResponse("Invalid request body"))
+		}
+	}
+
+	if req.ExpiresIn < 0 || time.Duration(req.ExpiresIn)*time.Second > maxShareExpiry {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse(fmt.Sprintf("expiresIn must be between 1 and %d seconds", int(maxShareExpiry.Seconds()))))
+	}
+	if req.MaxViewers < 0 || req.MaxViewers > maxShareViewers {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse(fmt.Sprintf("maxViewers must be between 0 and %d", maxShareViewers)))
+	}
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
+	if err != nil {
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	if err != nil {
+		log.Printf("Error getting session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(404, utils.ErrorResponse("Session not found"))
+	}
+
+	if !strings.EqualFold(sessionState.ProjectID, projectID) {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Session does not belong to this project"))
+	}
+
+	if !utils.IsSessionActive(sessionState.InternalStatus) {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Session is not active"))
+	}
+
+	if sessionState.PublicIP == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Session browser is not ready yet. Share links not available."))
+	}
+
+	// Links never outlive the session itself
+	now := time.Now()
+	expiry := defaultShareExpiry
+	if req.ExpiresIn > 0 {
+		expiry = time.Duration(req.ExpiresIn) * time.Second
+	}
+	expiresAt := now.Add(expiry)
+	if sessionState.ExpiresAtUnix > 0 && expiresAt.Unix() > sessionState.ExpiresAtUnix {
+		expiresAt = time.Unix(sessionState.ExpiresAtUnix, 0)
+	}
+
+	payload := utils.CDPSigningPayload{
+		SessionID:  sessionID,
+		ProjectID:  sessionState.ProjectID,
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  expiresAt.Unix(),
+		Nonce:      utils.GenerateRandomNonce(),
+		Scope:      utils.CDPTokenScopeView,
+		MaxViewers: req.MaxViewers,
+	}
+
+	// Viewers are bound by the project's CDP policy like any other client
+	project, err := utils.GetProjectMetadata(ctx, ddbClient, sessionState.ProjectID)
+	if err != nil {
+		log.Printf("Warning: could not load project %s metadata, share link carries no CDP policy: %v", sessionState.ProjectID, err)
+	} else {
+		payload.CDPPolicy = project.CDPPolicy
+	}
+
+	token, err := utils.CreateCDPToken(payload)
+	if err != nil {
+		log.Printf("Error creating share token for session %s: %v", sessionID, err)
+		utils.LogSessionError(sessionID, projectID, err, "create_share_token", nil)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to generate share link"))
+	}
+
+	// Keep an audit trail of who can watch the session; the link works even if this fails
+	eventDetail := map[string]interface{}{
+		"sessionId":  sessionID,
+		"tokenId":    payload.Nonce,
+		"expiresAt":  expiresAt.UTC().Format(time.RFC3339),
+		"maxViewers": req.MaxViewers,
+	}
+	if err := utils.AddSessionEvent(ctx, ddbClient, sessionID, "LiveViewShared", "wallcrawler.sessions-share", eventDetail); err != nil {
+		log.Printf("Error recording share event for session %s: %v", sessionID, err)
+	}
+
+	response := SessionShareResponse{
+		SessionID:             sessionID,
+		Scope:                 utils.CDPTokenScopeView,
+		SigningKey:            token,
+		DebuggerURL:           utils.CreateDebuggerURL(sessionState.PublicIP, token),
+		DebuggerFullscreenURL: utils.CreateDebuggerFullscreenURL(sessionState.PublicIP, token),
+		ExpiresAt:             expiresAt.UTC().Format(time.RFC3339),
+		MaxViewers:            req.MaxViewers,
+	}
+
+	log.Printf("Created view-only share link for session %s (expires %s, max viewers %d)", sessionID, response.ExpiresAt, req.MaxViewers)
+	return utils.CreateAPIResponse(200, utils.SuccessResponse(response))
+}
+
+func main() {
+	lambda.Start(func(ctx context.Context, event interface{}) (interface{}, error) {
+		parsedEvent, eventType, err := utils.ParseLambdaEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if eventType != utils.EventTypeAPIGateway {
+			return nil, fmt.Errorf("expected API Gateway event, got %v", eventType)
+		}
+
+		apiReq := parsedEvent.(events.APIGatewayProxyRequest)
+		return Handler(ctx, apiReq)
+	})
+}
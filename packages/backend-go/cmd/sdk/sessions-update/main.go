@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -73,16 +74,25 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	}
 
 	// Check if session is already terminated
-	if sessionState.Status == types.SessionStatusStopped ||
-		sessionState.Status == types.SessionStatusFailed {
-		log.Printf("Session %s is already terminated with status: %s", sessionID, sessionState.Status)
+	if utils.IsSessionTerminal(sessionState.InternalStatus) {
+		log.Printf("Session %s is already terminated with status: %s", sessionID, sessionState.InternalStatus)
 		return utils.CreateAPIResponse(200, utils.SuccessResponse(sessionState))
 	}
 
 	log.Printf("Processing termination request for session %s", sessionID)
 
-	// Update session status to STOPPED in DynamoDB
-	if err := utils.UpdateSessionStatus(ctx, ddbClient, sessionID, types.SessionStatusStopped); err != nil {
+	// Update session status to STOPPED in DynamoDB. The transition is rejected if the
+	// session ended since it was read; releasing it again is then a no-op.
+	err = utils.UpdateSessionStatus(ctx, ddbClient, sessionID, types.SessionStatusStopped)
+	var transitionErr *utils.SessionTransitionError
+	if errors.As(err, &transitionErr) && utils.IsSessionTerminal(transitionErr.From) {
+		log.Printf("Session %s ended as %s before it was released", sessionID, transitionErr.From)
+		if current, getErr := utils.GetSession(ctx, ddbClient, sessionID); getErr == nil {
+			sessionState = current
+		}
+		return utils.CreateAPIResponse(200, utils.SuccessResponse(sessionState))
+	}
+	if err != nil {
 		log.Printf("Error updating session status: %v", err)
 		utils.LogSessionError(sessionID, req.ProjectID, err, "update_status", nil)
 		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to update session status"))
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/wallcrawler/backend-go/internal/cdpproxy"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// controllerTimeout bounds how long the controller may take to pull the staged file
+const controllerTimeout = 30 * time.Second
+
+var errUploadTooLarge = errors.New("upload exceeds the project's size limit")
+
+type sessionUploadResponse struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	Path     string `json:"path"` // Location in the browser container, for DOM.setFileInputFiles
+}
+
+// Handler processes POST /v1/sessions/{id}/uploads (multipart file upload into the session's browser)
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	sessionID := request.PathParameters["id"]
+	if sessionID == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Missing session ID parameter"))
+	}
+
+	projectID := utils.GetAuthorizedProjectID(request.RequestContext.Authorizer)
+	if projectID == "" {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Unauthorized project access"))
+	}
+
+	if utils.ContextsBucketName == "" {
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Upload storage not configured"))
+	}
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
+	if err != nil {
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+	if err != nil {
+		log.Printf("Error getting session %s: %v", sessionID, err)
+		return utils.CreateAPIResponse(404, utils.ErrorResponse("Session not found"))
+	}
+
+	if !strings.EqualFold(sessionState.ProjectID, projectID) {
+		return utils.CreateAPIResponse(403, utils.ErrorResponse("Session does not belong to this project"))
+	}
+
+	if !utils.IsSessionActive(sessionState.InternalStatus) {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Session is not active"))
+	}
+
+	if sessionState.PublicIP == "" {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse("Session browser is not ready yet"))
+	}
+
+	// Projects without their own limit, or whose metadata can't be read, get the default cap
+	project, err := utils.GetProjectMetadata(ctx, ddbClient, sessionState.ProjectID)
+	if err != nil {
+		log.Printf("Error getting project %s, using default upload limit: %v", sessionState.ProjectID, err)
+	}
+	maxBytes := utils.ProjectMaxUploadBytes(project)
+
+	filename, content, err := readUploadedFile(request, maxBytes)
+	if errors.Is(err, errUploadTooLarge) {
+		return utils.CreateAPIResponse(413, utils.ErrorResponse(fmt.Sprintf("File exceeds the upload limit of %d bytes", maxBytes)))
+	}
+	if err != nil {
+		return utils.CreateAPIResponse(400, utils.ErrorResponse(fmt.Sprintf("Invalid upload: %v", err)))
+	}
+
+	uploadID := uuid.New().String()
+	key := utils.SessionUploadKey(sessionState.ProjectID, sessionID, uploadID, filename)
+
+	s3Client, err := utils.GetS3Client(ctx)
+	if err != nil {
+		log.Printf("Error getting S3 client: %v", err)
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to initialize storage"))
+	}
+
+	// Stage the file; the controller pulls it and deletes the staged copy
+	if _, err := s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(utils.ContextsBucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(content),
+	}); err != nil {
+		log.Printf("Error staging upload for session %s: %v", sessionID, err)
+		utils.LogSessionError(sessionID, projectID, err, "stage_upload", map[string]interface{}{"filename": filename})
+		return utils.CreateAPIResponse(500, utils.ErrorResponse("Failed to store upload"))
+	}
+
+	path, err := deliverUpload(ctx, sessionState.PublicIP, sessionID, sessionState.ProjectID, cdpproxy.UploadRequest{
+		UploadID: uploadID,
+		Filename: filename,
+		Size:     int64(len(content)),
+	})
+	if err != nil {
+		log.Printf("Error delivering upload to session %s: %v", sessionID, err)
+		utils.LogSessionError(sessionID, projectID, err, "deliver_upload", map[string]interface{}{"filename": filename})
+
+		if _, err := s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(utils.ContextsBucketName),
+			Key:    aws.String(key),
+		}); err != nil {
+			log.Printf("Error deleting staged upload %s: %v", key, err)
+		}
+		return utils.CreateAPIResponse(502, utils.ErrorResponse("Failed to deliver upload to the session browser"))
+	}
+
+	response := sessionUploadResponse{
+		ID:       uploadID,
+		Filename: filename,
+		Size:     int64(len(content)),
+		Path:     path,
+	}
+
+	return utils.CreateAPIResponse(200, utils.SuccessResponse(response))
+}
+
+// readUploadedFile returns the "file" part of a multipart/form-data body
+func readUploadedFile(request events.APIGatewayProxyRequest, maxBytes int64) (string, []byte, error) {
+	contentType := ""
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			contentType = value
+			break
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", nil, fmt.Errorf("expected a multipart/form-data body")
+	}
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		body, err = base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return "", nil, fmt.Errorf("malformed body encoding")
+		}
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return "", nil, fmt.Errorf("missing file field")
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("malformed multipart body")
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		content, err := io.ReadAll(io.LimitReader(part, maxBytes+1))
+		if err != nil {
+			return "", nil, fmt.Errorf("malformed multipart body")
+		}
+		if int64(len(content)) > maxBytes {
+			return "", nil, errUploadTooLarge
+		}
+		return utils.SanitizeFilename(part.FileName()), content, nil
+	}
+}
+
+// deliverUpload asks the session's controller to pull the staged file, authenticating
+// with a short-lived internal-scope token. It returns the file's path in the container.
+func deliverUpload(ctx context.Context, taskIP, sessionID, projectID string, upload cdpproxy.UploadRequest) (string, error) {
+	token, err := utils.CreateCDPToken(utils.CDPSigningPayload{
+		SessionID: sessionID,
+		ProjectID: projectID,
+		Scope:     utils.CDPTokenScopeInternal,
+		ExpiresAt: time.Now().Add(2 * time.Minute).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(upload)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, controllerTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, utils.CreateControllerURL(taskIP, "/internal/uploads", token), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("controller returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode controller response: %v", err)
+	}
+	return result.Path, nil
+}
+
+func main() {
+	lambda.Start(func(ctx context.Context, event interface{}) (interface{}, error) {
+		parsedEvent, eventType, err := utils.ParseLambdaEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if eventType != utils.EventTypeAPIGateway {
+			return nil, fmt.Errorf("expected API Gateway event, got %v", eventType)
+		}
+
+		apiReq := parsedEvent.(events.APIGatewayProxyRequest)
+		return Handler(ctx, apiReq)
+	})
+}
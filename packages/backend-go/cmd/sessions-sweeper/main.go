@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wallcrawler/backend-go/internal/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// orphanGracePeriod keeps tasks that were just launched from being stopped before their
+// session record points at them
+const orphanGracePeriod = 5 * time.Minute
+
+// Handler runs on a schedule. It times out sessions that are still running past their
+// expiresAt, then stops browser tasks that no live session owns.
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	log.Printf("Sweeping expired sessions (scheduled at %s)", event.Time.Format(time.RFC3339))
+
+	ddbClient, err := utils.GetDynamoDBClient(ctx)
+	if err != nil {
+		log.Printf("Error getting DynamoDB client: %v", err)
+		return err
+	}
+
+	cfg, err := utils.GetAWSConfig()
+	if err != nil {
+		log.Printf("Error loading AWS config: %v", err)
+		return err
+	}
+	ecsClient := ecs.NewFromConfig(cfg)
+
+	now := time.Now()
+
+	timedOut, err := sweepExpiredSessions(ctx, ddbClient, now)
+	if err != nil {
+		log.Printf("Error sweeping expired sessions: %v", err)
+		return err
+	}
+
+	stopped, err := reconcileOrphanedTasks(ctx, ddbClient, ecsClient, now)
+	if err != nil {
+		log.Printf("Error reconciling orphaned tasks: %v", err)
+		return err
+	}
+
+	log.Printf("Sweep complete: %d sessions timed out, %d orphaned tasks stopped", timedOut, stopped)
+	return nil
+}
+
+// sweepExpiredSessions times out running sessions past their expiresAt. Every non-terminal
+// internal status maps to the RUNNING SDK status, so one index partition covers them all.
+func sweepExpiredSessions(ctx context.Context, ddbClient *dynamodb.Client, now time.Time) (int, error) {
+	sessionIDs, err := utils.ListExpiredSessionIDs(ctx, ddbClient, types.SessionStatusRunning, now)
+	if err != nil {
+		return 0, err
+	}
+
+	timedOut := 0
+	for _, sessionID := range sessionIDs {
+		sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+		if err != nil {
+			// Already removed by the TTL; its task is handled as an orphan
+			log.Printf("Skipping expired session %s: %v", sessionID, err)
+			continue
+		}
+
+		err = utils.MarkSessionTimedOut(ctx, ddbClient, sessionID, now)
+		if errors.Is(err, utils.ErrSessionNotExpired) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error timing out session %s: %v", sessionID, err)
+			continue
+		}
+
+		if sessionState.ECSTaskARN != "" {
+			if err := utils.StopECSTaskWithReason(ctx, sessionState.ECSTaskARN, utils.TaskStopReasonTimedOut); err != nil {
+				log.Printf("Error stopping task %s for session %s: %v", sessionState.ECSTaskARN, sessionID, err)
+			}
+		}
+
+		// Recorded on the session and published to EventBridge for ecs-task-processor
+		detail := map[string]interface{}{
+			"sessionId":  sessionID,
+			"projectId":  sessionState.ProjectID,
+			"expiresAt":  sessionState.ExpiresAt,
+			"ecsTaskArn": sessionState.ECSTaskARN,
+		}
+		if err := utils.AddSessionEvent(ctx, ddbClient, sessionID, "SessionTimedOut", "wallcrawler.sessions-sweeper", detail); err != nil {
+			log.Printf("Error recording timeout for session %s: %v", sessionID, err)
+		}
+
+		var age time.Duration
+		if createdAt, err := time.Parse(time.RFC3339, sessionState.CreatedAt); err == nil {
+			age = now.Sub(createdAt)
+		}
+		utils.LogSessionTimeout(sessionID, sessionState.ProjectID, age)
+
+		log.Printf("Session %s timed out (expired at %s)", sessionID, sessionState.ExpiresAt)
+		timedOut++
+	}
+
+	return timedOut, nil
+}
+
+// reconcileOrphanedTasks stops running browser tasks whose session record is gone, has
+// ended, or belongs to a different task
+func reconcileOrphanedTasks(ctx context.Context, ddbClient *dynamodb.Client, ecsClient *ecs.Client, now time.Time) (int, error) {
+	var taskARNs []string
+	paginator := ecs.NewListTasksPaginator(ecsClient, &ecs.ListTasksInput{
+		Cluster:       aws.String(utils.ECSCluster),
+		Family:        aws.String(utils.ECSTaskDefFamily),
+		DesiredStatus: ecstypes.DesiredStatusRunning,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, err
+		}
+		taskARNs = append(taskARNs, page.TaskArns...)
+	}
+
+	stopped := 0
+	// DescribeTasks accepts at most 100 tasks per call
+	for start := 0; start < len(taskARNs); start += 100 {
+		end := start + 100
+		if end > len(taskARNs) {
+			end = len(taskARNs)
+		}
+
+		result, err := ecsClient.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(utils.ECSCluster),
+			Tasks:   taskARNs[start:end],
+		})
+		if err != nil {
+			return stopped, err
+		}
+
+		for _, task := range result.Tasks {
+			taskARN := aws.ToString(task.TaskArn)
+			if task.CreatedAt != nil && now.Sub(*task.CreatedAt) < orphanGracePeriod {
+				continue
+			}
+
+			sessionID := taskSessionID(task)
+			if sessionID == "" {
+				continue
+			}
+
+			sessionState, err := utils.GetSession(ctx, ddbClient, sessionID)
+			if err != nil && !errors.Is(err, utils.ErrSessionNotFound) {
+				log.Printf("Error getting session %s for task %s: %v", sessionID, taskARN, err)
+				continue
+			}
+			if err == nil && sessionOwnsTask(sessionState, taskARN) {
+				continue
+			}
+
+			if err := utils.StopECSTaskWithReason(ctx, taskARN, utils.TaskStopReasonOrphaned); err != nil {
+				log.Printf("Error stopping orphaned task %s: %v", taskARN, err)
+				continue
+			}
+
+			utils.LogECSTaskEvent(sessionID, taskARN, "ORPHANED", map[string]interface{}{
+				"session_found": err == nil,
+			})
+			log.Printf("Stopped orphaned task %s (session %s)", taskARN, sessionID)
+			stopped++
+		}
+	}
+
+	return stopped, nil
+}
+
+// sessionOwnsTask reports whether a live session is backed by the given task
+func sessionOwnsTask(sessionState *types.SessionState, taskARN string) bool {
+	if utils.IsSessionTerminal(sessionState.InternalStatus) {
+		return false
+	}
+	return sessionState.ECSTaskARN == "" || sessionState.ECSTaskARN == taskARN
+}
+
+// taskSessionID reads the SESSION_ID a task was launched with from its container overrides
+func taskSessionID(task ecstypes.Task) string {
+	if task.Overrides == nil {
+		return ""
+	}
+	for _, override := range task.Overrides.ContainerOverrides {
+		for _, env := range override.Environment {
+			if aws.ToString(env.Name) == "SESSION_ID" {
+				return aws.ToString(env.Value)
+			}
+		}
+	}
+	return ""
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,178 @@
+package cdpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// discoveryEndpoints are the Chrome HTTP endpoints whose payloads contain debugger URLs
+var discoveryEndpoints = map[string]bool{
+	"/json":         true,
+	"/json/list":    true,
+	"/json/version": true,
+	"/json/new":     true,
+}
+
+// isDiscoveryEndpoint reports whether the Chrome endpoint returns target URLs that need rewriting
+func isDiscoveryEndpoint(chromeEndpoint string) bool {
+	return discoveryEndpoints[strings.TrimSuffix(chromeEndpoint, "/")]
+}
+
+// mutatingEndpointPrefixes are Chrome HTTP endpoints that change browser state
+var mutatingEndpointPrefixes = []string{"/json/new", "/json/close/", "/json/activate/"}
+
+// isMutatingEndpoint reports whether the Chrome endpoint opens, closes or activates a target
+func isMutatingEndpoint(chromeEndpoint string) bool {
+	for _, prefix := range mutatingEndpointPrefixes {
+		if strings.HasPrefix(chromeEndpoint, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// discoveryRewriter rewrites Chrome discovery payloads so every URL points back through the proxy
+type discoveryRewriter struct {
+	chromeAddr string // Internal Chrome address, e.g. 127.0.0.1:9222
+	publicAddr string // Externally reachable proxy host:port
+	secure     bool   // Whether the client reached us over TLS
+	signingKey string // Caller's signing key, appended to every rewritten URL
+}
+
+// newDiscoveryRewriter builds a rewriter for the given client request
+func (p *CDPProxy) newDiscoveryRewriter(r *http.Request) *discoveryRewriter {
+	return &discoveryRewriter{
+		chromeAddr: p.chromeAddr,
+		publicAddr: p.publicAddr(r),
+		secure:     r.TLS != nil,
+		signingKey: r.URL.Query().Get("signingKey"),
+	}
+}
+
+// publicAddr returns the host:port clients used to reach the proxy
+func (p *CDPProxy) publicAddr(r *http.Request) string {
+	host := r.Host
+	if host == "" {
+		host = "localhost"
+	}
+
+	// Clients normally include the proxy port; add it when they relied on a default
+	if _, _, err := net.SplitHostPort(host); err != nil && p.port != "" {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), p.port)
+	}
+	return host
+}
+
+// Rewrite decodes a discovery payload, rewrites its URLs and re-encodes it.
+// Payloads that are not JSON are returned unchanged.
+func (d *discoveryRewriter) Rewrite(body []byte) ([]byte, error) {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body, nil
+	}
+
+	switch v := payload.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if target, ok := item.(map[string]interface{}); ok {
+				d.rewriteTarget(target)
+			}
+		}
+	case map[string]interface{}:
+		d.rewriteTarget(v)
+	}
+
+	rewritten, err := json.MarshalIndent(payload, "", "   ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rewritten discovery payload: %v", err)
+	}
+	return rewritten, nil
+}
+
+// rewriteTarget rewrites the debugger URLs of a single target description
+func (d *discoveryRewriter) rewriteTarget(target map[string]interface{}) {
+	if wsURL, ok := target["webSocketDebuggerUrl"].(string); ok && wsURL != "" {
+		target["webSocketDebuggerUrl"] = d.rewriteWebSocketURL(wsURL)
+	}
+
+	if frontendURL, ok := target["devtoolsFrontendUrl"].(string); ok && frontendURL != "" {
+		target["devtoolsFrontendUrl"] = d.rewriteFrontendURL(frontendURL)
+	}
+}
+
+// rewriteWebSocketURL maps ws://127.0.0.1:9222/devtools/... to the signed public proxy URL
+func (d *discoveryRewriter) rewriteWebSocketURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	scheme := "ws"
+	if d.secure {
+		scheme = "wss"
+	}
+
+	return d.signedURL(scheme, parsed.Path)
+}
+
+// rewriteFrontendURL points the DevTools frontend at the proxy and rewrites its ws= parameter.
+// Only the page URL carries the signing key; the proxy answers it with a cookie that
+// authenticates the frontend's own files.
+func (d *discoveryRewriter) rewriteFrontendURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	params := parsed.Query()
+	wsParam := params.Get("ws")
+	if wsParam == "" {
+		wsParam = params.Get("wss")
+	}
+	params.Del("ws")
+	params.Del("wss")
+
+	if wsParam != "" {
+		// The ws parameter is host:port/path without a scheme
+		target := strings.TrimPrefix(wsParam, d.chromeAddr)
+		if idx := strings.Index(target, "/"); idx > 0 {
+			target = target[idx:]
+		}
+		wsTarget := strings.TrimPrefix(d.signedURL("ws", target), "ws://")
+		if d.secure {
+			params.Set("wss", wsTarget)
+		} else {
+			params.Set("ws", wsTarget)
+		}
+	}
+	params.Set("signingKey", d.signingKey)
+
+	// Relative frontend URLs are served by Chrome itself, so route them through the proxy
+	if !parsed.IsAbs() {
+		parsed.Scheme = "http"
+		if d.secure {
+			parsed.Scheme = "https"
+		}
+		parsed.Host = d.publicAddr
+	}
+
+	parsed.RawQuery = params.Encode()
+	return parsed.String()
+}
+
+// signedURL builds scheme://publicAddr/path?signingKey=... for the caller
+func (d *discoveryRewriter) signedURL(scheme, path string) string {
+	u := url.URL{
+		Scheme: scheme,
+		Host:   d.publicAddr,
+		Path:   path,
+	}
+	if d.signingKey != "" {
+		u.RawQuery = url.Values{"signingKey": []string{d.signingKey}}.Encode()
+	}
+	return u.String()
+}
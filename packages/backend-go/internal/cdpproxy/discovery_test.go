@@ -0,0 +1,227 @@
+package cdpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// fakeChrome serves Chrome's discovery endpoints and a minimal DevTools frontend. Every
+// URL it returns points at its own address, as Chrome's loopback responses do.
+type fakeChrome struct {
+	server  *httptest.Server
+	addr    string
+	cookies []string // Cookie headers Chrome received
+}
+
+func newFakeChrome(t *testing.T) *fakeChrome {
+	t.Helper()
+
+	chrome := &fakeChrome{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/version", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Browser":"HeadlessChrome/139.0","Protocol-Version":"1.3","webSocketDebuggerUrl":"ws://%s/devtools/browser/b1"}`, chrome.addr)
+	})
+	targets := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"id":"PAGE1","type":"page","title":"Example","url":"https://example.com/",`+
+			`"devtoolsFrontendUrl":"/devtools/inspector.html?ws=%[1]s/devtools/page/PAGE1",`+
+			`"webSocketDebuggerUrl":"ws://%[1]s/devtools/page/PAGE1"}]`, chrome.addr)
+	}
+	mux.HandleFunc("/json", targets)
+	mux.HandleFunc("/json/list", targets)
+	mux.HandleFunc("/devtools/", func(w http.ResponseWriter, r *http.Request) {
+		chrome.cookies = append(chrome.cookies, r.Header.Values("Cookie")...)
+		switch r.URL.Path {
+		case "/devtools/inspector.html":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, `<!doctype html><script type="module" src="./entrypoints/inspector/inspector.js"></script>`)
+		case "/devtools/entrypoints/inspector/inspector.js":
+			w.Header().Set("Content-Type", "text/javascript")
+			io.WriteString(w, `import './main.js';`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	chrome.server = httptest.NewServer(mux)
+	t.Cleanup(chrome.server.Close)
+	chrome.addr = strings.TrimPrefix(chrome.server.URL, "http://")
+	return chrome
+}
+
+// newTestProxyServer serves the proxy's authenticated handler in front of Chrome
+func newTestProxyServer(t *testing.T, chromeAddr string) *httptest.Server {
+	t.Helper()
+
+	proxy := NewCDPProxy(chromeAddr, testSessionID, testProjectID)
+	server := httptest.NewServer(http.HandlerFunc(proxy.handleCDPRequest))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDiscoveryEndpointsAreRewritten(t *testing.T) {
+	chrome := newFakeChrome(t)
+	server := newTestProxyServer(t, chrome.addr)
+	publicAddr := strings.TrimPrefix(server.URL, "http://")
+	signingKey := signTestToken(t, nil)
+
+	for _, endpoint := range []string{"/json", "/json/list", "/json/version"} {
+		t.Run(endpoint, func(t *testing.T) {
+			resp, err := http.Get(server.URL + endpoint + "?signingKey=" + url.QueryEscape(signingKey))
+			if err != nil {
+				t.Fatalf("GET %s: %v", endpoint, err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, body %s", resp.StatusCode, body)
+			}
+			if strings.Contains(string(body), chrome.addr) {
+				t.Fatalf("response leaks Chrome's address %s: %s", chrome.addr, body)
+			}
+
+			var targets []map[string]interface{}
+			if endpoint == "/json/version" {
+				var version map[string]interface{}
+				if err := json.Unmarshal(body, &version); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				targets = append(targets, version)
+			} else if err := json.Unmarshal(body, &targets); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			for _, target := range targets {
+				wsURL, _ := target["webSocketDebuggerUrl"].(string)
+				assertSignedURL(t, "webSocketDebuggerUrl", wsURL, "ws", publicAddr, signingKey)
+
+				frontendURL, ok := target["devtoolsFrontendUrl"].(string)
+				if !ok {
+					continue
+				}
+				assertSignedURL(t, "devtoolsFrontendUrl", frontendURL, "http", publicAddr, signingKey)
+
+				parsed, _ := url.Parse(frontendURL)
+				ws := parsed.Query().Get("ws")
+				assertSignedURL(t, "devtoolsFrontendUrl ws=", "ws://"+ws, "ws", publicAddr, signingKey)
+			}
+		})
+	}
+}
+
+// assertSignedURL checks that a rewritten URL points at the proxy and carries the caller's signing key
+func assertSignedURL(t *testing.T, field, raw, scheme, publicAddr, signingKey string) {
+	t.Helper()
+
+	parsed, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		t.Fatalf("%s = %q, want a URL", field, raw)
+	}
+	if parsed.Scheme != scheme || parsed.Host != publicAddr {
+		t.Errorf("%s = %q, want %s://%s/...", field, raw, scheme, publicAddr)
+	}
+	if got := parsed.Query().Get("signingKey"); got != signingKey {
+		t.Errorf("%s = %q, want it signed with the caller's key", field, raw)
+	}
+}
+
+func TestDiscoveryRewriterUsesSecureSchemes(t *testing.T) {
+	rewriter := &discoveryRewriter{
+		chromeAddr: "127.0.0.1:9222",
+		publicAddr: "proxy.example.com:443",
+		secure:     true,
+		signingKey: "key",
+	}
+
+	body, err := rewriter.Rewrite([]byte(`[{"id":"PAGE1",` +
+		`"devtoolsFrontendUrl":"/devtools/inspector.html?ws=127.0.0.1:9222/devtools/page/PAGE1",` +
+		`"webSocketDebuggerUrl":"ws://127.0.0.1:9222/devtools/page/PAGE1"}]`))
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+
+	var targets []map[string]string
+	if err := json.Unmarshal(body, &targets); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got, want := targets[0]["webSocketDebuggerUrl"], "wss://proxy.example.com:443/devtools/page/PAGE1?signingKey=key"; got != want {
+		t.Errorf("webSocketDebuggerUrl = %q, want %q", got, want)
+	}
+
+	frontend, _ := url.Parse(targets[0]["devtoolsFrontendUrl"])
+	if frontend.Scheme != "https" || frontend.Query().Get("ws") != "" {
+		t.Errorf("devtoolsFrontendUrl = %q, want https with a wss= parameter", frontend)
+	}
+	if got, want := frontend.Query().Get("wss"), "proxy.example.com:443/devtools/page/PAGE1?signingKey=key"; got != want {
+		t.Errorf("devtoolsFrontendUrl wss = %q, want %q", got, want)
+	}
+}
+
+func TestDevToolsFrontendLoadsThroughProxy(t *testing.T) {
+	chrome := newFakeChrome(t)
+	server := newTestProxyServer(t, chrome.addr)
+
+	// Build the debugger URL the API hands out, pointed at the test proxy
+	serverURL, _ := url.Parse(server.URL)
+	t.Setenv("CDP_PROXY_PORT", serverURL.Port())
+	page := utils.CreatePageDebuggerURL(serverURL.Hostname(), signTestToken(t, nil), "PAGE1")
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	assertStatus(t, client, page, http.StatusOK)
+
+	// The frontend requests its scripts relative to inspector.html, without the signing key
+	pageURL, _ := url.Parse(page)
+	asset := pageURL.ResolveReference(&url.URL{Path: "./entrypoints/inspector/inspector.js"}).String()
+	assertStatus(t, client, asset, http.StatusOK)
+
+	// Without the cookie from the page response the asset is refused
+	assertStatus(t, http.DefaultClient, asset, http.StatusUnauthorized)
+
+	if len(chrome.cookies) > 0 {
+		t.Errorf("Chrome received cookies %v, want the proxy to strip them", chrome.cookies)
+	}
+}
+
+func TestDevToolsCookieIsBoundToTheSession(t *testing.T) {
+	chrome := newFakeChrome(t)
+	server := newTestProxyServer(t, chrome.addr)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/devtools/entrypoints/inspector/inspector.js", nil)
+	req.AddCookie(&http.Cookie{Name: devtoolsKeyCookie, Value: signTestToken(t, func(p *utils.CDPSigningPayload) {
+		p.SessionID = "sess_other"
+	})})
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+// assertStatus fetches a URL and checks the response status
+func assertStatus(t *testing.T, client *http.Client, target string, want int) {
+	t.Helper()
+
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("GET %s: status = %d, want %d (%s)", target, resp.StatusCode, want, body)
+	}
+}
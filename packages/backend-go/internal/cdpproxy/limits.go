@@ -0,0 +1,64 @@
+package cdpproxy
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// actionLimit caps the number of actions automation clients may send in a session.
+// An action is an Input.* dispatch; every other command is not counted and stays
+// available once the limit is reached.
+type actionLimit struct {
+	max       int64
+	onReached func(count int64)
+	reached   int32 // Set once the limit has been hit, accessed atomically
+}
+
+// SetActionLimit caps the session at max actions. onReached is called once, the first
+// time a command is refused. A max of 0 or less disables the limit.
+func (p *CDPProxy) SetActionLimit(max int, onReached func(count int64)) {
+	if max <= 0 {
+		p.actionLimit = nil
+		return
+	}
+	p.actionLimit = &actionLimit{max: int64(max), onReached: onReached}
+}
+
+// ActionCount returns the number of Input.* commands proxied since the proxy started
+func (p *CDPProxy) ActionCount() int64 {
+	return atomic.LoadInt64(&p.actions)
+}
+
+// countAction counts a client's Input.* command against the action limit.
+// It returns false when the command must be refused because the limit was reached.
+func (p *CDPProxy) countAction(client *ClientConnection, method string) bool {
+	if !strings.HasPrefix(method, "Input.") {
+		return true
+	}
+
+	limit := p.actionLimit
+	if limit == nil {
+		atomic.AddInt64(&p.actions, 1)
+		return true
+	}
+
+	for {
+		count := atomic.LoadInt64(&p.actions)
+		if count >= limit.max {
+			break
+		}
+		if atomic.CompareAndSwapInt64(&p.actions, count, count+1) {
+			return true
+		}
+	}
+
+	if atomic.CompareAndSwapInt32(&limit.reached, 0, 1) {
+		log.Printf("CDP Proxy: Action limit of %d reached for session %s, refusing further input", limit.max, p.sessionID)
+		if limit.onReached != nil {
+			go limit.onReached(limit.max)
+		}
+	}
+	p.recordPolicyRejection(client, method)
+	return false
+}
@@ -0,0 +1,94 @@
+package cdpproxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountActionWithoutLimit(t *testing.T) {
+	proxy := NewCDPProxy("127.0.0.1:9222", testSessionID, testProjectID)
+	client := &ClientConnection{}
+
+	methods := []string{
+		"Input.dispatchMouseEvent",
+		"Input.insertText",
+		"Page.navigate",
+		"Runtime.evaluate",
+		"InputDevice.fake", // Only the Input domain counts
+	}
+	for _, method := range methods {
+		if !proxy.countAction(client, method) {
+			t.Errorf("countAction(%q) refused a command without a limit", method)
+		}
+	}
+
+	if got := proxy.ActionCount(); got != 2 {
+		t.Errorf("ActionCount() = %d, want 2", got)
+	}
+	if client.Blocked != 0 {
+		t.Errorf("client.Blocked = %d, want 0", client.Blocked)
+	}
+}
+
+func TestCountActionRefusesInputPastLimit(t *testing.T) {
+	proxy := NewCDPProxy("127.0.0.1:9222", testSessionID, testProjectID)
+	client := &ClientConnection{}
+
+	reached := make(chan int64, 2)
+	proxy.SetActionLimit(2, func(count int64) { reached <- count })
+
+	steps := []struct {
+		method string
+		want   bool
+	}{
+		{"Input.dispatchKeyEvent", true},
+		{"Page.navigate", true},
+		{"Input.dispatchMouseEvent", true},
+		{"Input.dispatchMouseEvent", false}, // Third action
+		{"Runtime.evaluate", true},          // Other domains stay available
+		{"Input.insertText", false},
+	}
+	for i, step := range steps {
+		if got := proxy.countAction(client, step.method); got != step.want {
+			t.Errorf("step %d: countAction(%q) = %v, want %v", i, step.method, got, step.want)
+		}
+	}
+
+	if got := proxy.ActionCount(); got != 2 {
+		t.Errorf("ActionCount() = %d, want 2", got)
+	}
+	if client.Blocked != 2 {
+		t.Errorf("client.Blocked = %d, want 2", client.Blocked)
+	}
+	if got := proxy.PolicyRejections()["Input.dispatchMouseEvent"]; got != 1 {
+		t.Errorf("rejections of Input.dispatchMouseEvent = %d, want 1", got)
+	}
+
+	select {
+	case count := <-reached:
+		if count != 2 {
+			t.Errorf("onReached(%d), want onReached(2)", count)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onReached was not called")
+	}
+	select {
+	case <-reached:
+		t.Error("onReached was called more than once")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSetActionLimitDisables(t *testing.T) {
+	for _, max := range []int{0, -1} {
+		proxy := NewCDPProxy("127.0.0.1:9222", testSessionID, testProjectID)
+		proxy.SetActionLimit(1, nil)
+		proxy.SetActionLimit(max, nil)
+
+		for i := 0; i < 3; i++ {
+			if !proxy.countAction(&ClientConnection{}, "Input.dispatchKeyEvent") {
+				t.Fatalf("SetActionLimit(%d): action %d refused, want the limit disabled", max, i+1)
+			}
+		}
+	}
+}
@@ -0,0 +1,654 @@
+package cdpproxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Screencast settings for the live view
+const (
+	liveScreencastQuality   = 70
+	liveScreencastMaxWidth  = 1920
+	liveScreencastMaxHeight = 1080
+	liveActivePollInterval  = 1 * time.Second
+	liveCommandTimeout      = 10 * time.Second
+)
+
+// cdpMessage is a generic CDP frame exchanged with Chrome's browser endpoint
+type cdpMessage struct {
+	ID        int64           `json:"id,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *cdpRPCError    `json:"error,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+}
+
+// liveEvent is a JSON control message sent to live viewers alongside binary JPEG frames
+type liveEvent struct {
+	Type     string                 `json:"type"`
+	TargetID string                 `json:"targetId,omitempty"`
+	URL      string                 `json:"url,omitempty"`
+	Title    string                 `json:"title,omitempty"`
+	State    string                 `json:"state,omitempty"`  // Takeover control state
+	Reason   string                 `json:"reason,omitempty"` // Why control was denied
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// liveSession relays screencast frames from a page to a single live viewer
+type liveSession struct {
+	proxy  *CDPProxy
+	client *ClientConnection
+	viewer *websocket.Conn
+	chrome *websocket.Conn
+
+	viewerMu sync.Mutex
+	chromeMu sync.Mutex
+
+	nextID    int64
+	pending   map[int64]chan cdpMessage
+	pendingMu sync.Mutex
+
+	pinnedTarget string // Set when the viewer asked for a specific page
+	takeover     bool   // Viewer may request control and send input
+
+	stateMu         sync.Mutex
+	targetID        string // Page currently being streamed
+	targetSessionID string // Flattened CDP session attached to targetID
+
+	reevaluate chan struct{}
+	done       chan struct{}
+}
+
+// handleLivePage serves the live view page. The page opens /live/ws with the same signing key.
+func (p *CDPProxy) handleLivePage(w http.ResponseWriter, r *http.Request) {
+	if _, ok := p.authenticate(w, r); !ok {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(liveViewHTML))
+}
+
+// handleLiveSocket streams JPEG screencast frames of the active tab, or of the
+// page given in the target query parameter, to the viewer.
+func (p *CDPProxy) handleLiveSocket(w http.ResponseWriter, r *http.Request) {
+	payload, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := p.checkViewerLimit(payload); err != nil {
+		p.rejectRequest(w, r, http.StatusTooManyRequests, "Too Many Requests: "+err.Error(), err, payload)
+		return
+	}
+
+	viewerConn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("CDP Proxy: Failed to upgrade live view WebSocket: %v", err)
+		return
+	}
+	defer viewerConn.Close()
+
+	// Viewers count as attached clients so the session stays alive while watched
+	client, err := p.registerClient(r, payload)
+	if err != nil {
+		viewerConn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()))
+		return
+	}
+	defer p.unregisterClient(client)
+
+	browserURL, err := p.getBrowserWebSocketURL()
+	if err != nil {
+		log.Printf("CDP Proxy: Failed to resolve Chrome browser endpoint: %v", err)
+		viewerConn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "Chrome CDP unavailable"))
+		return
+	}
+
+	chromeConn, _, err := websocket.DefaultDialer.Dial(browserURL, nil)
+	if err != nil {
+		log.Printf("CDP Proxy: Failed to connect live view to Chrome: %v", err)
+		viewerConn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "Chrome CDP unavailable"))
+		return
+	}
+	defer chromeConn.Close()
+
+	session := &liveSession{
+		proxy:        p,
+		client:       client,
+		viewer:       viewerConn,
+		chrome:       chromeConn,
+		pending:      make(map[int64]chan cdpMessage),
+		pinnedTarget: r.URL.Query().Get("target"),
+		takeover:     r.URL.Query().Get("mode") == "takeover" && !payload.IsViewOnly(),
+		reevaluate:   make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+
+	log.Printf("CDP Proxy: Live view %s started for session %s", client.ID, payload.SessionID)
+	session.run()
+	log.Printf("CDP Proxy: Live view %s closed for session %s", client.ID, payload.SessionID)
+}
+
+// run drives the live session until either the viewer or Chrome goes away
+func (s *liveSession) run() {
+	go s.readChrome()
+	go s.followActiveTarget()
+
+	if _, err := s.call("Target.setDiscoverTargets", "", map[string]interface{}{"discover": true}); err != nil {
+		log.Printf("CDP Proxy: Live view %s failed to enable target discovery: %v", s.client.ID, err)
+	}
+	s.requestReevaluate()
+
+	// Reading also detects when the viewer disconnects
+	for {
+		messageType, data, err := s.viewer.ReadMessage()
+		if err != nil {
+			break
+		}
+		if !s.takeover || messageType != websocket.TextMessage {
+			continue
+		}
+		if err := s.handleTakeoverMessage(data); err != nil {
+			log.Printf("CDP Proxy: Live view %s failed to handle input: %v", s.client.ID, err)
+			break
+		}
+	}
+
+	// A viewer that disconnects while in control hands control back to automation
+	s.proxy.endTakeover(s.client)
+	s.close()
+}
+
+// close stops the session's background goroutines exactly once
+func (s *liveSession) close() {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+		s.chrome.Close()
+		s.viewer.Close()
+	}
+}
+
+// call sends a CDP command on the browser connection and waits for its response
+func (s *liveSession) call(method, sessionID string, params interface{}) (json.RawMessage, error) {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	id := s.nextCommandID()
+	response := make(chan cdpMessage, 1)
+
+	s.pendingMu.Lock()
+	s.pending[id] = response
+	s.pendingMu.Unlock()
+
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, id)
+		s.pendingMu.Unlock()
+	}()
+
+	if err := s.send(cdpMessage{ID: id, Method: method, Params: rawParams, SessionID: sessionID}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-response:
+		if msg.Error != nil {
+			return nil, fmt.Errorf("%s failed: %s", method, msg.Error.Message)
+		}
+		return msg.Result, nil
+	case <-s.done:
+		return nil, fmt.Errorf("live view closed")
+	case <-time.After(liveCommandTimeout):
+		return nil, fmt.Errorf("%s timed out", method)
+	}
+}
+
+// nextCommandID returns a unique id for a command on the browser connection
+func (s *liveSession) nextCommandID() int64 {
+	return atomic.AddInt64(&s.nextID, 1)
+}
+
+// send writes a command to Chrome without waiting for the response
+func (s *liveSession) send(msg cdpMessage) error {
+	s.chromeMu.Lock()
+	defer s.chromeMu.Unlock()
+	return s.chrome.WriteJSON(msg)
+}
+
+// writeViewer writes a frame to the viewer and meters it against the connection
+func (s *liveSession) writeViewer(messageType int, data []byte) error {
+	s.viewerMu.Lock()
+	defer s.viewerMu.Unlock()
+
+	if err := s.viewer.WriteMessage(messageType, data); err != nil {
+		return err
+	}
+	atomic.AddInt64(&s.client.BytesOut, int64(len(data)))
+	atomic.AddInt64(&s.proxy.bytesOut, int64(len(data)))
+	return nil
+}
+
+// writeEvent sends a JSON control message to the viewer
+func (s *liveSession) writeEvent(event liveEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.writeViewer(websocket.TextMessage, data)
+}
+
+// readChrome dispatches command responses and handles screencast and target events
+func (s *liveSession) readChrome() {
+	defer s.close()
+
+	for {
+		var msg cdpMessage
+		if err := s.chrome.ReadJSON(&msg); err != nil {
+			select {
+			case <-s.done:
+			default:
+				log.Printf("CDP Proxy: Live view %s lost Chrome connection: %v", s.client.ID, err)
+			}
+			return
+		}
+
+		if msg.ID != 0 {
+			s.pendingMu.Lock()
+			response, ok := s.pending[msg.ID]
+			s.pendingMu.Unlock()
+			if ok {
+				response <- msg
+			}
+			continue
+		}
+
+		switch msg.Method {
+		case "Page.screencastFrame":
+			if err := s.handleScreencastFrame(msg); err != nil {
+				log.Printf("CDP Proxy: Live view %s failed to relay frame: %v", s.client.ID, err)
+				return
+			}
+		case "Target.targetCreated", "Target.targetDestroyed", "Target.targetInfoChanged":
+			s.requestReevaluate()
+		}
+	}
+}
+
+// handleScreencastFrame forwards a frame of the current target as JPEG and acknowledges it
+func (s *liveSession) handleScreencastFrame(msg cdpMessage) error {
+	s.stateMu.Lock()
+	current := s.targetSessionID
+	s.stateMu.Unlock()
+
+	// Frames from a target we already switched away from are dropped
+	if msg.SessionID != current {
+		return nil
+	}
+
+	var frame struct {
+		Data      string                 `json:"data"`
+		Metadata  map[string]interface{} `json:"metadata"`
+		SessionID int64                  `json:"sessionId"`
+	}
+	if err := json.Unmarshal(msg.Params, &frame); err != nil {
+		return nil
+	}
+
+	// Acknowledge first so Chrome can start producing the next frame
+	ack := cdpMessage{
+		ID:        s.nextCommandID(),
+		Method:    "Page.screencastFrameAck",
+		SessionID: msg.SessionID,
+	}
+	ack.Params, _ = json.Marshal(map[string]interface{}{"sessionId": frame.SessionID})
+	if err := s.send(ack); err != nil {
+		return err
+	}
+
+	jpeg, err := base64.StdEncoding.DecodeString(frame.Data)
+	if err != nil {
+		return nil
+	}
+
+	if err := s.writeEvent(liveEvent{Type: "frame", Metadata: frame.Metadata}); err != nil {
+		return err
+	}
+	return s.writeViewer(websocket.BinaryMessage, jpeg)
+}
+
+// requestReevaluate asks followActiveTarget to re-check which page should be streamed
+func (s *liveSession) requestReevaluate() {
+	select {
+	case s.reevaluate <- struct{}{}:
+	default:
+	}
+}
+
+// followActiveTarget keeps the screencast attached to the most recently active page.
+// Chrome orders /json by last activation, so the first page there is the active tab.
+func (s *liveSession) followActiveTarget() {
+	ticker := time.NewTicker(liveActivePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.reevaluate:
+		}
+
+		page, err := s.activePage()
+		if err != nil {
+			continue
+		}
+
+		s.stateMu.Lock()
+		current := s.targetID
+		s.stateMu.Unlock()
+
+		if page.ID == current {
+			continue
+		}
+
+		if err := s.switchTarget(page); err != nil {
+			log.Printf("CDP Proxy: Live view %s failed to switch to target %s: %v", s.client.ID, page.ID, err)
+		}
+	}
+}
+
+// activePage returns the page to stream: the pinned page if one was requested, otherwise the active tab
+func (s *liveSession) activePage() (*PageInfo, error) {
+	if s.pinnedTarget == "" {
+		return s.proxy.getPageInfo()
+	}
+
+	pages, err := s.proxy.listPages()
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		if page.ID == s.pinnedTarget {
+			return &page, nil
+		}
+	}
+	return nil, fmt.Errorf("target %s not found", s.pinnedTarget)
+}
+
+// switchTarget stops the current screencast and starts one on the given page
+func (s *liveSession) switchTarget(page *PageInfo) error {
+	s.stateMu.Lock()
+	previousSession := s.targetSessionID
+	s.stateMu.Unlock()
+
+	if previousSession != "" {
+		s.call("Page.stopScreencast", previousSession, map[string]interface{}{})
+		s.call("Target.detachFromTarget", "", map[string]interface{}{"sessionId": previousSession})
+	}
+
+	result, err := s.call("Target.attachToTarget", "", map[string]interface{}{
+		"targetId": page.ID,
+		"flatten":  true,
+	})
+	if err != nil {
+		return err
+	}
+
+	var attached struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.Unmarshal(result, &attached); err != nil {
+		return err
+	}
+
+	s.stateMu.Lock()
+	s.targetID = page.ID
+	s.targetSessionID = attached.SessionID
+	s.stateMu.Unlock()
+
+	if _, err := s.call("Page.enable", attached.SessionID, map[string]interface{}{}); err != nil {
+		return err
+	}
+	if _, err := s.call("Page.startScreencast", attached.SessionID, map[string]interface{}{
+		"format":        "jpeg",
+		"quality":       liveScreencastQuality,
+		"maxWidth":      liveScreencastMaxWidth,
+		"maxHeight":     liveScreencastMaxHeight,
+		"everyNthFrame": 1,
+	}); err != nil {
+		return err
+	}
+
+	return s.writeEvent(liveEvent{
+		Type:     "target",
+		TargetID: page.ID,
+		URL:      page.URL,
+		Title:    page.Title,
+	})
+}
+
+// getBrowserWebSocketURL returns Chrome's browser-level debugger endpoint
+func (p *CDPProxy) getBrowserWebSocketURL() (string, error) {
+	resp, err := http.Get(fmt.Sprintf("http://%s/json/version", p.chromeAddr))
+	if err != nil {
+		return "", fmt.Errorf("failed to get browser version: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var version struct {
+		WebSocketDebuggerUrl string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", fmt.Errorf("failed to decode browser version: %v", err)
+	}
+	if version.WebSocketDebuggerUrl == "" {
+		return "", fmt.Errorf("browser endpoint not reported by Chrome")
+	}
+	return version.WebSocketDebuggerUrl, nil
+}
+
+// Pages returns Chrome's page targets, most recently active first
+func (p *CDPProxy) Pages() ([]PageInfo, error) {
+	return p.listPages()
+}
+
+// listPages returns Chrome's page targets, most recently active first
+func (p *CDPProxy) listPages() ([]PageInfo, error) {
+	resp, err := http.Get(fmt.Sprintf("http://%s/json/list", p.chromeAddr))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pages: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var targets []PageInfo
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("failed to decode page list: %v", err)
+	}
+
+	pages := make([]PageInfo, 0, len(targets))
+	for _, target := range targets {
+		if target.Type == "page" {
+			pages = append(pages, target)
+		}
+	}
+	return pages, nil
+}
+
+// liveViewHTML is the self-contained live view page. It reads the signing key
+// (and optional target) from its own URL and renders JPEG frames onto a canvas.
+// Served from /takeover it also lets a human take control and forwards input.
+const liveViewHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Wallcrawler Live View</title>
+<style>
+  html, body { margin: 0; height: 100%; background: #111; color: #ddd; font: 13px system-ui, sans-serif; }
+  body { display: flex; flex-direction: column; }
+  #bar { display: flex; align-items: center; gap: 8px; padding: 6px 10px; background: #222; white-space: nowrap; overflow: hidden; }
+  #bar .status { color: #8a8; }
+  #page { overflow: hidden; text-overflow: ellipsis; flex: 1; }
+  #control { display: none; }
+  body.takeover #control { display: inline-block; }
+  body.controlling canvas { outline: 2px solid #d84; cursor: default; }
+  #stage { flex: 1; display: flex; align-items: center; justify-content: center; min-height: 0; }
+  canvas { max-width: 100%; max-height: 100%; background: #000; }
+  body.fullscreen #bar { display: none; }
+</style>
+</head>
+<body>
+<div id="bar"><span class="status" id="status">connecting</span><span id="page"></span><button id="control">Take control</button></div>
+<div id="stage"><canvas id="screen" tabindex="0"></canvas></div>
+<script>
+(function () {
+  var params = new URLSearchParams(location.search);
+  var takeover = location.pathname === '/takeover';
+  if (params.get('fullscreen') === 'true') document.body.classList.add('fullscreen');
+  if (takeover) document.body.classList.add('takeover');
+
+  var canvas = document.getElementById('screen');
+  var context = canvas.getContext('2d');
+  var statusEl = document.getElementById('status');
+  var pageEl = document.getElementById('page');
+  var controlEl = document.getElementById('control');
+  var metadata = null;
+  var controlling = false;
+
+  var query = new URLSearchParams();
+  query.set('signingKey', params.get('signingKey') || '');
+  if (params.get('target')) query.set('target', params.get('target'));
+  if (takeover) query.set('mode', 'takeover');
+
+  var scheme = location.protocol === 'https:' ? 'wss:' : 'ws:';
+  var socket = new WebSocket(scheme + '//' + location.host + '/live/ws?' + query.toString());
+  socket.binaryType = 'blob';
+
+  function send(message) {
+    if (socket.readyState === WebSocket.OPEN) socket.send(JSON.stringify(message));
+  }
+
+  function setControlling(value) {
+    controlling = value;
+    document.body.classList.toggle('controlling', value);
+    controlEl.textContent = value ? 'Release control' : 'Take control';
+    statusEl.textContent = value ? 'in control' : 'live';
+    if (value) canvas.focus();
+  }
+
+  socket.onopen = function () { statusEl.textContent = 'live'; };
+  socket.onclose = function () { setControlling(false); statusEl.textContent = 'disconnected'; };
+
+  socket.onmessage = function (event) {
+    if (typeof event.data === 'string') {
+      var message = JSON.parse(event.data);
+      if (message.type === 'target') {
+        pageEl.textContent = (message.title || '') + ' ' + (message.url || '');
+      } else if (message.type === 'frame') {
+        metadata = message.metadata;
+      } else if (message.type === 'control') {
+        if (message.state === 'denied') statusEl.textContent = 'control denied: ' + (message.reason || '');
+        else setControlling(message.state === 'granted');
+      }
+      return;
+    }
+    createImageBitmap(event.data).then(function (bitmap) {
+      if (canvas.width !== bitmap.width || canvas.height !== bitmap.height) {
+        canvas.width = bitmap.width;
+        canvas.height = bitmap.height;
+      }
+      context.drawImage(bitmap, 0, 0);
+      bitmap.close();
+    });
+  };
+
+  if (!takeover) return;
+
+  controlEl.onclick = function () { send({ type: controlling ? 'release' : 'takeover' }); };
+
+  // Map canvas coordinates to page CSS pixels using the latest frame metadata
+  function position(event) {
+    var rect = canvas.getBoundingClientRect();
+    var width = metadata ? metadata.deviceWidth : canvas.width;
+    var height = metadata ? metadata.deviceHeight : canvas.height;
+    return {
+      x: (event.clientX - rect.left) * width / rect.width,
+      y: (event.clientY - rect.top) * height / rect.height
+    };
+  }
+
+  function modifiers(event) {
+    return (event.altKey ? 1 : 0) | (event.ctrlKey ? 2 : 0) | (event.metaKey ? 4 : 0) | (event.shiftKey ? 8 : 0);
+  }
+
+  var buttons = ['left', 'middle', 'right'];
+  function mouse(type) {
+    return function (event) {
+      if (!controlling) return;
+      event.preventDefault();
+      var point = position(event);
+      send({
+        type: 'mouse', event: type, x: point.x, y: point.y,
+        button: type === 'mouseMoved' ? 'none' : buttons[event.button] || 'none',
+        clickCount: type === 'mouseMoved' ? 0 : event.detail || 1,
+        modifiers: modifiers(event)
+      });
+    };
+  }
+
+  canvas.addEventListener('mousedown', mouse('mousePressed'));
+  canvas.addEventListener('mouseup', mouse('mouseReleased'));
+  canvas.addEventListener('mousemove', mouse('mouseMoved'));
+  canvas.addEventListener('contextmenu', function (event) { if (controlling) event.preventDefault(); });
+  canvas.addEventListener('wheel', function (event) {
+    if (!controlling) return;
+    event.preventDefault();
+    var point = position(event);
+    send({ type: 'mouse', event: 'mouseWheel', x: point.x, y: point.y, deltaX: event.deltaX, deltaY: event.deltaY, modifiers: modifiers(event) });
+  }, { passive: false });
+
+  function key(type) {
+    return function (event) {
+      if (!controlling) return;
+      event.preventDefault();
+      var printable = type === 'keyDown' && event.key.length === 1;
+      send({
+        type: 'key', event: printable ? 'keyDown' : (type === 'keyDown' ? 'rawKeyDown' : type),
+        key: event.key, code: event.code, keyCode: event.keyCode,
+        text: printable ? event.key : '', modifiers: modifiers(event)
+      });
+    };
+  }
+
+  canvas.addEventListener('keydown', key('keyDown'));
+  canvas.addEventListener('keyup', key('keyUp'));
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,157 @@
+package cdpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+// observerDeniedMethods are refused for read-only (observer) connections
+var observerDeniedMethods = []string{
+	"Input.*",
+	"Runtime.evaluate",
+	"Runtime.callFunctionOn",
+	"Runtime.compileScript",
+	"Runtime.runScript",
+}
+
+// viewOnlyMethods are the only commands view-scoped tokens may send: enough to watch a
+// page through a screencast or screenshots, nothing that reads page content or state
+var viewOnlyMethods = []string{
+	"Page.startScreencast",
+	"Page.stopScreencast",
+	"Page.screencastFrameAck",
+	"Page.captureScreenshot",
+}
+
+// cdpCommand is the subset of a client->Chrome CDP frame needed for enforcement
+type cdpCommand struct {
+	ID        *int64 `json:"id"`
+	Method    string `json:"method"`
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+// cdpErrorResponse is the synthetic response returned for rejected commands
+type cdpErrorResponse struct {
+	ID        int64       `json:"id"`
+	Error     cdpRPCError `json:"error"`
+	SessionID string      `json:"sessionId,omitempty"`
+}
+
+type cdpRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// cdpErrorServerError is the JSON-RPC code Chrome itself uses for rejected commands
+const cdpErrorServerError = -32000
+
+// parseCDPCommand extracts the command id and method from a text frame.
+// ok is false for frames that are not CDP commands.
+func parseCDPCommand(message []byte) (cdpCommand, bool) {
+	var cmd cdpCommand
+	if err := json.Unmarshal(message, &cmd); err != nil {
+		return cmd, false
+	}
+	if cmd.ID == nil || cmd.Method == "" {
+		return cmd, false
+	}
+	return cmd, true
+}
+
+// newCDPErrorFrame builds the encoded error response for a rejected command
+func newCDPErrorFrame(cmd cdpCommand, message string) []byte {
+	frame, _ := json.Marshal(cdpErrorResponse{
+		ID: *cmd.ID,
+		Error: cdpRPCError{
+			Code:    cdpErrorServerError,
+			Message: message,
+		},
+		SessionID: cmd.SessionID,
+	})
+	return frame
+}
+
+// methodPolicy evaluates CDP methods against one or more layered policies.
+// A method must be permitted by every layer to be allowed.
+type methodPolicy struct {
+	layers   []*types.CDPPolicy
+	viewOnly bool // Set for view-scoped tokens; only viewOnlyMethods pass
+}
+
+// newMethodPolicy combines the controller default policy with a token policy
+func newMethodPolicy(policies ...*types.CDPPolicy) *methodPolicy {
+	mp := &methodPolicy{}
+	for _, policy := range policies {
+		if policy != nil {
+			mp.layers = append(mp.layers, policy)
+		}
+	}
+	return mp
+}
+
+// Check returns an error describing why the method is blocked, or nil if it is allowed
+func (mp *methodPolicy) Check(method string) error {
+	if mp == nil {
+		return nil
+	}
+
+	if mp.viewOnly && !matchesAny(method, viewOnlyMethods) {
+		return fmt.Errorf("'%s' is not allowed for view-only access", method)
+	}
+
+	for _, policy := range mp.layers {
+		if policy.ReadOnly && matchesAny(method, observerDeniedMethods) {
+			return fmt.Errorf("'%s' is not allowed for read-only connections", method)
+		}
+		if matchesAny(method, policy.Deny) {
+			return fmt.Errorf("'%s' is blocked by session policy", method)
+		}
+		if len(policy.Allow) > 0 && !matchesAny(method, policy.Allow) {
+			return fmt.Errorf("'%s' is not in the session allow list", method)
+		}
+	}
+
+	return nil
+}
+
+// matchesAny reports whether method matches one of the patterns.
+// Patterns are exact method names, "Domain.*", a bare "Domain", or "*".
+func matchesAny(method string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		switch {
+		case pattern == "":
+			continue
+		case pattern == "*":
+			return true
+		case strings.HasSuffix(pattern, ".*"):
+			if strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		case !strings.Contains(pattern, "."):
+			if strings.HasPrefix(method, pattern+".") {
+				return true
+			}
+		case pattern == method:
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePolicy decodes a JSON policy document, as loaded from the controller environment
+func ParsePolicy(raw string) (*types.CDPPolicy, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var policy types.CDPPolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return nil, fmt.Errorf("invalid CDP policy: %v", err)
+	}
+	return &policy, nil
+}
@@ -0,0 +1,200 @@
+package cdpproxy
+
+import (
+	"testing"
+
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		method   string
+		patterns []string
+		want     bool
+	}{
+		{"Runtime.evaluate", []string{"Runtime.evaluate"}, true},
+		{"Runtime.evaluateAsync", []string{"Runtime.evaluate"}, false}, // Exact names do not match as prefixes
+		{"Runtime.evaluate", []string{"Runtime.*"}, true},
+		{"RuntimeX.evaluate", []string{"Runtime.*"}, false},
+		{"Runtime.evaluate", []string{"Runtime"}, true},
+		{"RuntimeX.evaluate", []string{"Runtime"}, false},
+		{"Page.navigate", []string{"*"}, true},
+		{"Page.navigate", []string{" Page.navigate "}, true},
+		{"Page.navigate", []string{"", "Runtime.*"}, false},
+		{"Page.navigate", nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesAny(tt.method, tt.patterns); got != tt.want {
+			t.Errorf("matchesAny(%q, %q) = %v, want %v", tt.method, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestMethodPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []*types.CDPPolicy
+		method   string
+		allowed  bool
+	}{
+		{
+			name:    "no policy",
+			method:  "Runtime.evaluate",
+			allowed: true,
+		},
+		{
+			name:     "nil policies are skipped",
+			policies: []*types.CDPPolicy{nil, nil},
+			method:   "Runtime.evaluate",
+			allowed:  true,
+		},
+		{
+			name:     "denied domain",
+			policies: []*types.CDPPolicy{{Deny: []string{"Browser.*"}}},
+			method:   "Browser.close",
+		},
+		{
+			name:     "denied exact method",
+			policies: []*types.CDPPolicy{{Deny: []string{"Page.navigate"}}},
+			method:   "Page.navigate",
+		},
+		{
+			name:     "exact deny leaves similar names alone",
+			policies: []*types.CDPPolicy{{Deny: []string{"Page.navigate"}}},
+			method:   "Page.navigateToHistoryEntry",
+			allowed:  true,
+		},
+		{
+			name:     "allowed by allow list",
+			policies: []*types.CDPPolicy{{Allow: []string{"Page.*", "Runtime.evaluate"}}},
+			method:   "Runtime.evaluate",
+			allowed:  true,
+		},
+		{
+			name:     "missing from allow list",
+			policies: []*types.CDPPolicy{{Allow: []string{"Page.*", "Runtime.evaluate"}}},
+			method:   "Runtime.callFunctionOn",
+		},
+		{
+			name:     "deny wins over allow",
+			policies: []*types.CDPPolicy{{Allow: []string{"Page.*"}, Deny: []string{"Page.navigate"}}},
+			method:   "Page.navigate",
+		},
+		{
+			name:     "read-only refuses input",
+			policies: []*types.CDPPolicy{{ReadOnly: true}},
+			method:   "Input.dispatchMouseEvent",
+		},
+		{
+			name:     "read-only refuses script evaluation",
+			policies: []*types.CDPPolicy{{ReadOnly: true}},
+			method:   "Runtime.callFunctionOn",
+		},
+		{
+			name:     "read-only allows screenshots",
+			policies: []*types.CDPPolicy{{ReadOnly: true}},
+			method:   "Page.captureScreenshot",
+			allowed:  true,
+		},
+		{
+			name: "token policy cannot widen the default",
+			policies: []*types.CDPPolicy{
+				{Deny: []string{"Browser.*"}},
+				{Allow: []string{"*"}},
+			},
+			method: "Browser.close",
+		},
+		{
+			name: "token policy narrows the default",
+			policies: []*types.CDPPolicy{
+				{Deny: []string{"Browser.*"}},
+				{Allow: []string{"Page.*"}},
+			},
+			method: "Runtime.evaluate",
+		},
+		{
+			name: "allowed by every layer",
+			policies: []*types.CDPPolicy{
+				{Deny: []string{"Browser.*"}},
+				{Allow: []string{"Page.*"}},
+			},
+			method:  "Page.navigate",
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newMethodPolicy(tt.policies...).Check(tt.method)
+			if tt.allowed && err != nil {
+				t.Errorf("Check(%q) = %v, want allowed", tt.method, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("Check(%q) allowed the method, want it blocked", tt.method)
+			}
+		})
+	}
+}
+
+func TestNilMethodPolicyAllowsEverything(t *testing.T) {
+	var policy *methodPolicy
+	if err := policy.Check("Browser.close"); err != nil {
+		t.Errorf("Check() = %v, want nil", err)
+	}
+}
+
+func TestViewOnlyPolicy(t *testing.T) {
+	tests := []struct {
+		method  string
+		allowed bool
+	}{
+		{"Page.startScreencast", true},
+		{"Page.stopScreencast", true},
+		{"Page.screencastFrameAck", true},
+		{"Page.captureScreenshot", true},
+
+		// Commands that read page content or state
+		{"Page.captureSnapshot", false},
+		{"DOM.getOuterHTML", false},
+		{"DOM.querySelector", false},
+		{"DOM.performSearch", false},
+		{"Runtime.getProperties", false},
+		{"Storage.getCookies", false},
+		{"Network.getResponseBody", false},
+		{"Target.attachToTarget", false},
+		{"Target.setAutoAttach", false},
+
+		// Input and script evaluation
+		{"Input.dispatchMouseEvent", false},
+		{"Runtime.evaluate", false},
+		{"Page.navigate", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			policy := newMethodPolicy()
+			policy.viewOnly = true
+
+			err := policy.Check(tt.method)
+			if tt.allowed && err != nil {
+				t.Errorf("Check(%q) = %v, want allowed", tt.method, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("Check(%q) allowed the method, want it blocked", tt.method)
+			}
+		})
+	}
+}
+
+func TestViewOnlyPolicyKeepsProjectPolicy(t *testing.T) {
+	policy := newMethodPolicy(&types.CDPPolicy{Deny: []string{"Page.captureScreenshot"}})
+	policy.viewOnly = true
+
+	if err := policy.Check("Page.captureScreenshot"); err == nil {
+		t.Error("Check() allowed a method the project denies")
+	}
+	if err := policy.Check("Page.startScreencast"); err != nil {
+		t.Errorf("Check(%q) = %v, want allowed", "Page.startScreencast", err)
+	}
+}
@@ -6,16 +6,33 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/wallcrawler/backend-go/internal/types"
 	"github.com/wallcrawler/backend-go/internal/utils"
 )
 
+// WebSocket close codes sent to CDP clients when the browser goes away underneath them
+const (
+	CloseBrowserRestarted = 4001 // Chrome crashed and was restarted; reconnect to continue
+	CloseBrowserCrashed   = 4002 // Chrome crashed and will not be restarted
+	CloseResourceLimit    = 4003 // The session exceeded a resource limit and is stopping
+)
+
+// devtoolsKeyCookie carries the signing key to the DevTools frontend's own requests.
+// inspector.html is opened with ?signingKey=, but the scripts, styles and images it loads
+// next cannot add the key to their URLs.
+const devtoolsKeyCookie = "wallcrawler_devtools_key"
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins for simplicity
@@ -25,10 +42,54 @@ var upgrader = websocket.Upgrader{
 // CDPProxy represents a simplified CDP proxy that only handles authentication
 type CDPProxy struct {
 	chromeAddr      string
+	sessionID       string // Session this container was started for
+	projectID       string // Project that owns the session
+	port            string
 	server          *http.Server
-	hasConnection   bool
+	clients         map[string]*ClientConnection // Live CDP clients keyed by connection ID
 	connectionMutex sync.RWMutex
-	onDisconnect    func() // Callback when connection drops
+	nextClientID    uint64
+	onDisconnect    func() // Callback when the last connection drops
+
+	defaultPolicy    *types.CDPPolicy // Policy loaded at controller start, applied to every client
+	policyMutex      sync.Mutex
+	policyRejections map[string]int64 // Rejected commands by method
+
+	recorder *Recorder // Optional CDP traffic recorder
+
+	uploadHandler func(ctx context.Context, req UploadRequest) (string, error) // Pulls staged uploads into the container
+
+	// Actions sent by clients, capped by the session's resource limits
+	actions     int64 // Accessed atomically
+	actionLimit *actionLimit
+
+	// Human takeover: while active, automation clients' Input.* commands are held
+	takeoverMutex    sync.Mutex
+	takeover         TakeoverState
+	takeoverReleased chan struct{} // Closed when the current takeover ends
+	takeoverTimer    *time.Timer   // Ends the takeover after takeoverTimeout
+	onTakeoverChange func(state TakeoverState)
+
+	// Session-wide traffic totals, including clients that have disconnected
+	bytesIn  int64 // Client -> Chrome
+	bytesOut int64 // Chrome -> Client
+}
+
+// ClientConnection describes a single client attached to the proxy over WebSocket
+type ClientConnection struct {
+	ID          string    `json:"id"`
+	RemoteAddr  string    `json:"remoteAddr"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	TargetPath  string    `json:"targetPath"`
+	BytesIn     int64     `json:"bytesIn"`  // Client -> Chrome
+	BytesOut    int64     `json:"bytesOut"` // Chrome -> Client
+	Blocked     int64     `json:"blockedCommands"`
+	Scope       string    `json:"scope,omitempty"` // "view" for read-only share links
+
+	tokenID    string // Nonce of the signing key, used to enforce max viewers
+	policy     *methodPolicy
+	notify     func(frame []byte) error      // Writes a proxy-generated frame to the client
+	disconnect func(code int, reason string) // Closes the client socket with a close code
 }
 
 // PageInfo represents information about a Chrome page/target
@@ -43,15 +104,64 @@ type PageInfo struct {
 	Description          string `json:"description,omitempty"`
 }
 
-// NewCDPProxy creates a new simplified CDP proxy instance
-func NewCDPProxy(chromeAddr string) *CDPProxy {
+// NewCDPProxy creates a new simplified CDP proxy instance bound to a single session.
+// Tokens issued for any other session or project are rejected.
+func NewCDPProxy(chromeAddr, sessionID, projectID string) *CDPProxy {
 	return &CDPProxy{
 		chromeAddr: chromeAddr,
+		sessionID:  sessionID,
+		projectID:  projectID,
+		clients:    make(map[string]*ClientConnection),
+
+		policyRejections: make(map[string]int64),
 	}
 }
 
-// Start initializes and starts the CDP proxy server
+// SetDefaultPolicy sets the CDP method policy applied to every client, in addition
+// to any policy carried in the client's signing key.
+func (p *CDPProxy) SetDefaultPolicy(policy *types.CDPPolicy) {
+	p.defaultPolicy = policy
+}
+
+// SetRecorder enables recording of every proxied CDP frame
+func (p *CDPProxy) SetRecorder(recorder *Recorder) {
+	p.recorder = recorder
+}
+
+// PolicyRejections returns the number of rejected commands per CDP method
+func (p *CDPProxy) PolicyRejections() map[string]int64 {
+	p.policyMutex.Lock()
+	defer p.policyMutex.Unlock()
+
+	counts := make(map[string]int64, len(p.policyRejections))
+	for method, count := range p.policyRejections {
+		counts[method] = count
+	}
+	return counts
+}
+
+// recordPolicyRejection counts a rejected command for the client and the proxy
+func (p *CDPProxy) recordPolicyRejection(client *ClientConnection, method string) {
+	atomic.AddInt64(&client.Blocked, 1)
+
+	p.policyMutex.Lock()
+	p.policyRejections[method]++
+	p.policyMutex.Unlock()
+}
+
+// TrafficTotals returns the bytes proxied in each direction since the proxy started,
+// across WebSocket frames and HTTP responses.
+func (p *CDPProxy) TrafficTotals() (bytesIn, bytesOut int64) {
+	return atomic.LoadInt64(&p.bytesIn), atomic.LoadInt64(&p.bytesOut)
+}
+
+// Start initializes and starts the CDP proxy server. It refuses to start without a
+// session and project, since tokens could not be bound to the container otherwise.
 func (p *CDPProxy) Start(port string) error {
+	if p.sessionID == "" || p.projectID == "" {
+		return fmt.Errorf("CDP proxy requires a session ID and project ID")
+	}
+	p.port = port
 	mux := http.NewServeMux()
 
 	// Main endpoint with auth
@@ -60,6 +170,17 @@ func (p *CDPProxy) Start(port string) error {
 	// Health check endpoint (no auth required)
 	mux.HandleFunc("/health", p.handleHealth)
 
+	// Connected client registry (auth required)
+	mux.HandleFunc("/connections", p.handleConnections)
+
+	// Live view and human takeover pages, and their screencast stream (auth required)
+	mux.HandleFunc("/live", p.handleLivePage)
+	mux.HandleFunc("/live/ws", p.handleLiveSocket)
+	mux.HandleFunc("/takeover", p.handleLivePage)
+
+	// Called by the API to deliver uploaded files (internal-scope auth required)
+	mux.HandleFunc("/internal/uploads", p.handleInternalUpload)
+
 	p.server = &http.Server{
 		Addr:    ":" + port,
 		Handler: mux,
@@ -111,55 +232,271 @@ func (p *CDPProxy) Stop() error {
 
 // handleCDPRequest handles authentication and routes CDP requests
 func (p *CDPProxy) handleCDPRequest(w http.ResponseWriter, r *http.Request) {
+	payload, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	log.Printf("CDP Proxy: Authenticated request for session %s", payload.SessionID)
+
+	// Handle WebSocket vs HTTP requests
+	if r.Header.Get("Upgrade") == "websocket" {
+		p.handleWebSocketConnection(w, r, payload)
+		return
+	}
+
+	p.handleHTTPRequest(w, r, payload)
+}
+
+// authenticate validates the signing key on the request and checks that it was issued
+// for this container's session. On failure the error response has already been written.
+func (p *CDPProxy) authenticate(w http.ResponseWriter, r *http.Request) (*utils.CDPSigningPayload, bool) {
 	// Extract and validate signing key from query parameters
 	signingKey := r.URL.Query().Get("signingKey")
+	fromCookie := false
+	if signingKey == "" && isDevToolsFileRequest(r) {
+		if cookie, err := r.Cookie(devtoolsKeyCookie); err == nil {
+			signingKey = cookie.Value
+			fromCookie = true
+		}
+	}
 	if signingKey == "" {
-		log.Printf("CDP Proxy: Missing signing key for %s %s", r.Method, r.URL.Path)
-		http.Error(w, "Unauthorized: Missing signing key", 401)
-		return
+		p.rejectRequest(w, r, http.StatusUnauthorized, "Unauthorized: Missing signing key", fmt.Errorf("missing signing key"), nil)
+		return nil, false
 	}
 
 	// Validate the signing key
 	payload, err := utils.ValidateCDPToken(signingKey)
 	if err != nil {
-		log.Printf("CDP Proxy: Invalid signing key: %v", err)
-		http.Error(w, "Unauthorized: Invalid signing key", 401)
-		return
+		p.rejectRequest(w, r, http.StatusUnauthorized, "Unauthorized: Invalid signing key", err, nil)
+		return nil, false
 	}
 
-	log.Printf("CDP Proxy: Authenticated request for session %s", payload.SessionID)
+	// Make sure the token was minted for the session running in this container
+	if err := p.authorizePayload(r, payload); err != nil {
+		p.rejectRequest(w, r, http.StatusForbidden, "Forbidden: "+err.Error(), err, payload)
+		return nil, false
+	}
 
-	// Handle WebSocket vs HTTP requests
-	if r.Header.Get("Upgrade") == "websocket" {
-		p.handleWebSocketConnection(w, r, payload)
-		return
+	// Let the frontend page's follow-up requests authenticate with the same key
+	if !fromCookie && isDevToolsFileRequest(r) {
+		http.SetCookie(w, &http.Cookie{
+			Name:     devtoolsKeyCookie,
+			Value:    signingKey,
+			Path:     "/devtools/",
+			Expires:  time.Unix(payload.ExpiresAt, 0),
+			Secure:   r.TLS != nil,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
 	}
 
-	p.handleHTTPRequest(w, r, payload)
+	return payload, true
+}
+
+// isDevToolsFileRequest reports whether the request fetches a file of the DevTools frontend
+// bundled with Chrome, as opposed to opening a /devtools/ debugger socket
+func isDevToolsFileRequest(r *http.Request) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		strings.HasPrefix(r.URL.Path, "/devtools/") &&
+		!websocket.IsWebSocketUpgrade(r)
+}
+
+// authorizePayload checks the token claims against the proxy's session identity
+func (p *CDPProxy) authorizePayload(r *http.Request, payload *utils.CDPSigningPayload) error {
+	if payload.SessionID != p.sessionID {
+		return fmt.Errorf("signing key was not issued for this session")
+	}
+
+	if payload.ProjectID != p.projectID {
+		return fmt.Errorf("signing key was not issued for this project")
+	}
+
+	// Enforce the IP binding when the token carries one
+	if payload.IPAddress != "" {
+		remoteIP := clientIP(r)
+		if remoteIP == "" || !ipEqual(remoteIP, payload.IPAddress) {
+			return fmt.Errorf("signing key is not valid from this IP address")
+		}
+	}
+
+	return nil
+}
+
+// rejectRequest writes an auth error response and records the rejection as a session error
+func (p *CDPProxy) rejectRequest(w http.ResponseWriter, r *http.Request, status int, message string, reason error, payload *utils.CDPSigningPayload) {
+	log.Printf("CDP Proxy: Rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, reason)
+
+	metadata := map[string]interface{}{
+		"remote_addr": r.RemoteAddr,
+		"path":        r.URL.Path,
+		"status_code": status,
+	}
+	if payload != nil {
+		metadata["token_session_id"] = payload.SessionID
+		metadata["token_project_id"] = payload.ProjectID
+		if payload.IPAddress != "" {
+			metadata["token_ip_address"] = payload.IPAddress
+		}
+	}
+	utils.LogSessionError(p.sessionID, p.projectID, reason, "cdp_proxy_auth", metadata)
+
+	http.Error(w, message, status)
+}
+
+// clientIP returns the IP address of the directly connected client
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ipEqual compares two IP addresses, tolerating IPv4-mapped IPv6 forms
+func ipEqual(a, b string) bool {
+	ipA := net.ParseIP(strings.TrimSpace(a))
+	ipB := net.ParseIP(strings.TrimSpace(b))
+	if ipA == nil || ipB == nil {
+		return false
+	}
+	return ipA.Equal(ipB)
+}
+
+// checkViewerLimit reports whether the signing key still has room for another connection.
+// It is checked before the WebSocket upgrade so over-limit clients get a plain HTTP 429;
+// registerClient checks again once the socket is open.
+func (p *CDPProxy) checkViewerLimit(payload *utils.CDPSigningPayload) error {
+	p.connectionMutex.RLock()
+	defer p.connectionMutex.RUnlock()
+	return p.viewerLimitError(payload)
+}
+
+// viewerLimitError must be called with connectionMutex held
+func (p *CDPProxy) viewerLimitError(payload *utils.CDPSigningPayload) error {
+	if payload.MaxViewers <= 0 {
+		return nil
+	}
+	viewers := 0
+	for _, existing := range p.clients {
+		if existing.tokenID == payload.Nonce {
+			viewers++
+		}
+	}
+	if viewers >= payload.MaxViewers {
+		return fmt.Errorf("signing key allows at most %d concurrent viewers", payload.MaxViewers)
+	}
+	return nil
 }
 
-// SetConnectionState updates the connection state
-func (p *CDPProxy) SetConnectionState(connected bool) {
+// registerClient adds a client to the live connection registry. It is called only after
+// the WebSocket upgrade succeeded, so a failed upgrade never counts as a connect and
+// disconnect. It fails when the signing key already has as many connections as its max
+// viewers allows.
+func (p *CDPProxy) registerClient(r *http.Request, payload *utils.CDPSigningPayload) (*ClientConnection, error) {
 	p.connectionMutex.Lock()
 	defer p.connectionMutex.Unlock()
 
-	wasConnected := p.hasConnection
-	p.hasConnection = connected
+	if err := p.viewerLimitError(payload); err != nil {
+		return nil, err
+	}
+
+	policy := newMethodPolicy(p.defaultPolicy, payload.CDPPolicy)
+	policy.viewOnly = payload.IsViewOnly()
+
+	p.nextClientID++
+	client := &ClientConnection{
+		ID:          fmt.Sprintf("conn_%d", p.nextClientID),
+		RemoteAddr:  r.RemoteAddr,
+		ConnectedAt: time.Now(),
+		TargetPath:  r.URL.Path,
+		Scope:       payload.Scope,
+		tokenID:     payload.Nonce,
+		policy:      policy,
+	}
+	p.clients[client.ID] = client
 
-	// Trigger disconnect callback if we just disconnected
-	if wasConnected && !connected && p.onDisconnect != nil {
+	log.Printf("CDP Proxy: Client %s connected from %s (%d active)", client.ID, client.RemoteAddr, len(p.clients))
+	return client, nil
+}
+
+// unregisterClient removes a client and fires the disconnect callback once the last client leaves
+func (p *CDPProxy) unregisterClient(client *ClientConnection) {
+	p.connectionMutex.Lock()
+	defer p.connectionMutex.Unlock()
+
+	if _, ok := p.clients[client.ID]; !ok {
+		return
+	}
+	delete(p.clients, client.ID)
+
+	log.Printf("CDP Proxy: Client %s disconnected after %v (%d active)",
+		client.ID, time.Since(client.ConnectedAt).Round(time.Second), len(p.clients))
+
+	// Only report idle when nobody is left attached
+	if len(p.clients) == 0 && p.onDisconnect != nil {
 		go p.onDisconnect()
 	}
 }
 
-// IsConnected returns the current connection state
+// IsConnected reports whether at least one client is attached
 func (p *CDPProxy) IsConnected() bool {
 	p.connectionMutex.RLock()
 	defer p.connectionMutex.RUnlock()
-	return p.hasConnection
+	return len(p.clients) > 0
+}
+
+// ConnectionCount returns the number of attached clients
+func (p *CDPProxy) ConnectionCount() int {
+	p.connectionMutex.RLock()
+	defer p.connectionMutex.RUnlock()
+	return len(p.clients)
+}
+
+// Connections returns a snapshot of the attached clients, oldest first
+func (p *CDPProxy) Connections() []ClientConnection {
+	p.connectionMutex.RLock()
+	defer p.connectionMutex.RUnlock()
+
+	connections := make([]ClientConnection, 0, len(p.clients))
+	for _, client := range p.clients {
+		connections = append(connections, ClientConnection{
+			ID:          client.ID,
+			RemoteAddr:  client.RemoteAddr,
+			ConnectedAt: client.ConnectedAt,
+			TargetPath:  client.TargetPath,
+			Scope:       client.Scope,
+			BytesIn:     atomic.LoadInt64(&client.BytesIn),
+			BytesOut:    atomic.LoadInt64(&client.BytesOut),
+			Blocked:     atomic.LoadInt64(&client.Blocked),
+		})
+	}
+
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ConnectedAt.Before(connections[j].ConnectedAt)
+	})
+	return connections
+}
+
+// DisconnectClients closes every CDP client socket with the given close code and returns
+// how many were closed. Used when the browser behind the proxy has been replaced.
+func (p *CDPProxy) DisconnectClients(code int, reason string) int {
+	p.connectionMutex.RLock()
+	var closers []func(code int, reason string)
+	for _, client := range p.clients {
+		if client.disconnect != nil {
+			closers = append(closers, client.disconnect)
+		}
+	}
+	p.connectionMutex.RUnlock()
+
+	for _, closeClient := range closers {
+		closeClient(code, reason)
+	}
+	return len(closers)
 }
 
-// SetOnDisconnect sets the callback function to be called when connection drops
+// SetOnDisconnect sets the callback function to be called when the last connection drops
 func (p *CDPProxy) SetOnDisconnect(callback func()) {
 	p.onDisconnect = callback
 }
@@ -168,9 +505,10 @@ func (p *CDPProxy) SetOnDisconnect(callback func()) {
 func (p *CDPProxy) handleWebSocketConnection(w http.ResponseWriter, r *http.Request, payload *utils.CDPSigningPayload) {
 	log.Printf("CDP Proxy: WebSocket connection for session %s", payload.SessionID)
 
-	// Set connection state to true
-	p.SetConnectionState(true)
-	defer p.SetConnectionState(false)
+	if err := p.checkViewerLimit(payload); err != nil {
+		p.rejectRequest(w, r, http.StatusTooManyRequests, "Too Many Requests: "+err.Error(), err, payload)
+		return
+	}
 
 	// Upgrade client connection
 	clientConn, err := upgrader.Upgrade(w, r, nil)
@@ -180,6 +518,16 @@ func (p *CDPProxy) handleWebSocketConnection(w http.ResponseWriter, r *http.Requ
 	}
 	defer clientConn.Close()
 
+	// Track the client for as long as the socket is open
+	client, err := p.registerClient(r, payload)
+	if err != nil {
+		// Another connection took the last slot after the pre-upgrade check
+		clientConn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()))
+		return
+	}
+	defer p.unregisterClient(client)
+
 	// Determine Chrome WebSocket endpoint
 	chromeEndpoint, err := p.getChromeWebSocketEndpoint(r.URL.Path)
 	if err != nil {
@@ -202,7 +550,7 @@ func (p *CDPProxy) handleWebSocketConnection(w http.ResponseWriter, r *http.Requ
 	log.Printf("CDP Proxy: WebSocket proxy established for session %s", payload.SessionID)
 
 	// Proxy messages bidirectionally
-	p.proxyWebSocketMessages(clientConn, chromeConn)
+	p.proxyWebSocketMessages(client, clientConn, chromeConn)
 
 	log.Printf("CDP Proxy: WebSocket connection closed for session %s", payload.SessionID)
 }
@@ -213,6 +561,12 @@ func (p *CDPProxy) handleHTTPRequest(w http.ResponseWriter, r *http.Request, pay
 	chromeEndpoint := p.getChromeHTTPEndpoint(r.URL.Path)
 	targetURL := fmt.Sprintf("http://%s%s", p.chromeAddr, chromeEndpoint)
 
+	// View-scoped tokens may list targets but not open, close or activate them
+	if payload.IsViewOnly() && isMutatingEndpoint(chromeEndpoint) {
+		http.Error(w, "Forbidden: not allowed for view-only access", http.StatusForbidden)
+		return
+	}
+
 	// Preserve query parameters (except signingKey)
 	if r.URL.RawQuery != "" {
 		params, _ := url.ParseQuery(r.URL.RawQuery)
@@ -222,12 +576,18 @@ func (p *CDPProxy) handleHTTPRequest(w http.ResponseWriter, r *http.Request, pay
 		}
 	}
 
+	// Discovery payloads reference Chrome's loopback address and must be rewritten
+	var rewriter *discoveryRewriter
+	if isDiscoveryEndpoint(chromeEndpoint) {
+		rewriter = p.newDiscoveryRewriter(r)
+	}
+
 	log.Printf("CDP Proxy: Proxying HTTP %s to %s for session %s", r.Method, targetURL, payload.SessionID)
-	p.proxyHTTPRequest(w, r, targetURL)
+	p.proxyHTTPRequest(w, r, targetURL, rewriter)
 }
 
-// proxyHTTPRequest proxies HTTP requests to Chrome
-func (p *CDPProxy) proxyHTTPRequest(w http.ResponseWriter, r *http.Request, targetURL string) {
+// proxyHTTPRequest proxies HTTP requests to Chrome, optionally rewriting discovery payloads
+func (p *CDPProxy) proxyHTTPRequest(w http.ResponseWriter, r *http.Request, targetURL string, rewriter *discoveryRewriter) {
 	// Create request to Chrome
 	req, err := http.NewRequest(r.Method, targetURL, r.Body)
 	if err != nil {
@@ -238,13 +598,18 @@ func (p *CDPProxy) proxyHTTPRequest(w http.ResponseWriter, r *http.Request, targ
 
 	// Copy relevant headers (exclude auth headers)
 	for key, values := range r.Header {
-		if key != "Authorization" && !strings.HasPrefix(key, "X-") {
+		if key != "Authorization" && key != "Cookie" && !strings.HasPrefix(key, "X-") {
 			for _, value := range values {
 				req.Header.Add(key, value)
 			}
 		}
 	}
 
+	// We need a plain body to rewrite discovery payloads
+	if rewriter != nil {
+		req.Header.Del("Accept-Encoding")
+	}
+
 	// Make request to Chrome
 	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
@@ -255,6 +620,11 @@ func (p *CDPProxy) proxyHTTPRequest(w http.ResponseWriter, r *http.Request, targ
 	}
 	defer resp.Body.Close()
 
+	if rewriter != nil && resp.StatusCode == http.StatusOK {
+		p.writeRewrittenDiscovery(w, resp, rewriter)
+		return
+	}
+
 	// Copy response headers
 	for key, values := range resp.Header {
 		for _, value := range values {
@@ -264,15 +634,89 @@ func (p *CDPProxy) proxyHTTPRequest(w http.ResponseWriter, r *http.Request, targ
 
 	// Copy status code and body
 	w.WriteHeader(resp.StatusCode)
-	_, err = io.Copy(w, resp.Body)
+	written, err := io.Copy(w, resp.Body)
+	atomic.AddInt64(&p.bytesOut, written)
 	if err != nil {
 		log.Printf("CDP Proxy: Error copying response body: %v", err)
 	}
 }
 
+// writeRewrittenDiscovery rewrites a Chrome discovery response and writes it to the client
+func (p *CDPProxy) writeRewrittenDiscovery(w http.ResponseWriter, resp *http.Response, rewriter *discoveryRewriter) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("CDP Proxy: Error reading discovery response: %v", err)
+		http.Error(w, "Chrome CDP unavailable", 502)
+		return
+	}
+
+	rewritten, err := rewriter.Rewrite(body)
+	if err != nil {
+		log.Printf("CDP Proxy: Error rewriting discovery response: %v", err)
+		http.Error(w, "Error rewriting Chrome response", 500)
+		return
+	}
+
+	// Copy response headers, except those describing the original body
+	for key, values := range resp.Header {
+		if key == "Content-Length" || key == "Content-Encoding" {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(rewritten)))
+
+	w.WriteHeader(resp.StatusCode)
+	written, err := w.Write(rewritten)
+	atomic.AddInt64(&p.bytesOut, int64(written))
+	if err != nil {
+		log.Printf("CDP Proxy: Error writing discovery response: %v", err)
+	}
+}
+
 // proxyWebSocketMessages handles bidirectional WebSocket message proxying
-func (p *CDPProxy) proxyWebSocketMessages(clientConn, chromeConn *websocket.Conn) {
+func (p *CDPProxy) proxyWebSocketMessages(client *ClientConnection, clientConn, chromeConn *websocket.Conn) {
 	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Both directions may write to the client (policy errors and Chrome frames)
+	var clientWriteMu sync.Mutex
+	writeClient := func(messageType int, message []byte) error {
+		clientWriteMu.Lock()
+		defer clientWriteMu.Unlock()
+		return clientConn.WriteMessage(messageType, message)
+	}
+
+	// Held takeover input is flushed from its own goroutine, alongside the read loop
+	var chromeWriteMu sync.Mutex
+	writeChrome := func(messageType int, message []byte) error {
+		chromeWriteMu.Lock()
+		defer chromeWriteMu.Unlock()
+		if err := chromeConn.WriteMessage(messageType, message); err != nil {
+			return err
+		}
+		atomic.AddInt64(&client.BytesIn, int64(len(message)))
+		atomic.AddInt64(&p.bytesIn, int64(len(message)))
+		return nil
+	}
+	forwardHeld := func(message []byte) error {
+		return writeChrome(websocket.TextMessage, message)
+	}
+	held := &heldInput{}
+
+	p.connectionMutex.Lock()
+	client.notify = func(frame []byte) error {
+		p.recorder.Record(DirectionProxyToClient, client.ID, frame)
+		return writeClient(websocket.TextMessage, frame)
+	}
+	client.disconnect = func(code int, reason string) {
+		clientConn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+		clientConn.Close()
+	}
+	p.connectionMutex.Unlock()
 
 	// Client -> Chrome
 	go func() {
@@ -286,7 +730,41 @@ func (p *CDPProxy) proxyWebSocketMessages(clientConn, chromeConn *websocket.Conn
 				return
 			}
 
-			if err := chromeConn.WriteMessage(messageType, message); err != nil {
+			p.recorder.Record(DirectionClientToChrome, client.ID, message)
+
+			// Enforce the method policy on client commands
+			if messageType == websocket.TextMessage {
+				if cmd, ok := parseCDPCommand(message); ok {
+					if err := client.policy.Check(cmd.Method); err != nil {
+						p.recordPolicyRejection(client, cmd.Method)
+						log.Printf("CDP Proxy: Blocked %s from client %s: %v", cmd.Method, client.ID, err)
+						errorFrame := newCDPErrorFrame(cmd, err.Error())
+						p.recorder.Record(DirectionProxyToClient, client.ID, errorFrame)
+						if err := writeClient(websocket.TextMessage, errorFrame); err != nil {
+							log.Printf("CDP Proxy: Error writing to client: %v", err)
+							return
+						}
+						continue
+					}
+
+					if !p.countAction(client, cmd.Method) {
+						errorFrame := newCDPErrorFrame(cmd, "session action limit reached")
+						p.recorder.Record(DirectionProxyToClient, client.ID, errorFrame)
+						if err := writeClient(websocket.TextMessage, errorFrame); err != nil {
+							log.Printf("CDP Proxy: Error writing to client: %v", err)
+							return
+						}
+						continue
+					}
+
+					// Automation input waits while a human is in control
+					if p.holdAutomationInput(ctx, client, held, cmd.Method, message, forwardHeld) {
+						continue
+					}
+				}
+			}
+
+			if err := writeChrome(messageType, message); err != nil {
 				log.Printf("CDP Proxy: Error writing to Chrome: %v", err)
 				return
 			}
@@ -304,10 +782,14 @@ func (p *CDPProxy) proxyWebSocketMessages(clientConn, chromeConn *websocket.Conn
 				return
 			}
 
-			if err := clientConn.WriteMessage(messageType, message); err != nil {
+			p.recorder.Record(DirectionChromeToClient, client.ID, message)
+
+			if err := writeClient(messageType, message); err != nil {
 				log.Printf("CDP Proxy: Error writing to client: %v", err)
 				return
 			}
+			atomic.AddInt64(&client.BytesOut, int64(len(message)))
+			atomic.AddInt64(&p.bytesOut, int64(len(message)))
 		}
 	}()
 
@@ -377,6 +859,30 @@ func (p *CDPProxy) getPageInfo() (*PageInfo, error) {
 	return nil, fmt.Errorf("no pages found")
 }
 
+// handleConnections lists the clients currently attached to the proxy
+func (p *CDPProxy) handleConnections(w http.ResponseWriter, r *http.Request) {
+	if _, ok := p.authenticate(w, r); !ok {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	connections := p.Connections()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessionId":        p.sessionID,
+		"count":            len(connections),
+		"connections":      connections,
+		"policyRejections": p.PolicyRejections(),
+		"takeover":         p.Takeover(),
+		"timestamp":        time.Now(),
+	})
+}
+
 // handleHealth provides simple health check endpoint
 func (p *CDPProxy) handleHealth(w http.ResponseWriter, r *http.Request) {
 	// Test Chrome connectivity
@@ -0,0 +1,162 @@
+package cdpproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const (
+	testSessionID = "sess_test"
+	testProjectID = "proj_Test"
+)
+
+func TestMain(m *testing.M) {
+	// Tokens are signed with a fixed development key instead of the Secrets Manager secret
+	os.Setenv("WALLCRAWLER_JWT_SIGNING_KEY", "cdpproxy-test-signing-key")
+	os.Exit(m.Run())
+}
+
+// signTestToken mints a signing key for the test session, applying overrides to the payload
+func signTestToken(t *testing.T, override func(*utils.CDPSigningPayload)) string {
+	t.Helper()
+
+	payload := utils.CDPSigningPayload{
+		SessionID: testSessionID,
+		ProjectID: testProjectID,
+	}
+	if override != nil {
+		override(&payload)
+	}
+
+	token, err := utils.CreateCDPToken(payload)
+	if err != nil {
+		t.Fatalf("CreateCDPToken() error = %v", err)
+	}
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name       string
+		signingKey string
+		remoteAddr string
+		wantStatus int // 0 when the request is authenticated
+	}{
+		{
+			name:       "matching session and project",
+			signingKey: signTestToken(t, nil),
+		},
+		{
+			name:       "missing signing key",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid signing key",
+			signingKey: "not-a-token",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "session mismatch",
+			signingKey: signTestToken(t, func(p *utils.CDPSigningPayload) {
+				p.SessionID = "sess_other"
+			}),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "project mismatch",
+			signingKey: signTestToken(t, func(p *utils.CDPSigningPayload) {
+				p.ProjectID = "proj_other"
+			}),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "project differing only in case",
+			signingKey: signTestToken(t, func(p *utils.CDPSigningPayload) {
+				p.ProjectID = "proj_test"
+			}),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "matching IP binding",
+			signingKey: signTestToken(t, func(p *utils.CDPSigningPayload) {
+				p.IPAddress = "203.0.113.7"
+			}),
+			remoteAddr: "203.0.113.7:51234",
+		},
+		{
+			name: "IPv4-mapped IP binding",
+			signingKey: signTestToken(t, func(p *utils.CDPSigningPayload) {
+				p.IPAddress = "203.0.113.7"
+			}),
+			remoteAddr: "[::ffff:203.0.113.7]:51234",
+		},
+		{
+			name: "IP mismatch",
+			signingKey: signTestToken(t, func(p *utils.CDPSigningPayload) {
+				p.IPAddress = "203.0.113.7"
+			}),
+			remoteAddr: "198.51.100.2:51234",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	proxy := NewCDPProxy("127.0.0.1:9222", testSessionID, testProjectID)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/json/version"
+			if tt.signingKey != "" {
+				target += "?signingKey=" + tt.signingKey
+			}
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			if tt.remoteAddr != "" {
+				r.RemoteAddr = tt.remoteAddr
+			}
+			w := httptest.NewRecorder()
+
+			payload, ok := proxy.authenticate(w, r)
+
+			if tt.wantStatus == 0 {
+				if !ok || payload == nil {
+					t.Fatalf("authenticate() rejected the request with %d: %s", w.Code, w.Body.String())
+				}
+				if payload.SessionID != testSessionID {
+					t.Errorf("payload.SessionID = %q, want %q", payload.SessionID, testSessionID)
+				}
+				return
+			}
+			if ok {
+				t.Fatalf("authenticate() accepted the request, want status %d", tt.wantStatus)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestStartRequiresSessionAndProject(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID string
+		projectID string
+	}{
+		{"missing session", "", testProjectID},
+		{"missing project", testSessionID, ""},
+		{"missing both", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := NewCDPProxy("127.0.0.1:9222", tt.sessionID, tt.projectID)
+			if err := proxy.Start("0"); err == nil {
+				proxy.Stop()
+				t.Fatal("Start() succeeded, want an error")
+			}
+		})
+	}
+}
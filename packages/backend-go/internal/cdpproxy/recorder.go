@@ -0,0 +1,179 @@
+package cdpproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Frame directions recorded in the traffic log
+const (
+	DirectionClientToChrome = "client->chrome"
+	DirectionChromeToClient = "chrome->client"
+	DirectionProxyToClient  = "proxy->client" // Synthetic frames generated by the proxy
+)
+
+// DefaultRecorderMaxFrameBytes caps the size of a single recorded frame
+const DefaultRecorderMaxFrameBytes = 64 * 1024
+
+// truncatedFieldBytes is the length kept from oversized string fields (screenshots, bodies)
+const truncatedFieldBytes = 256
+
+// RecordedFrame is a single line of the JSONL traffic log
+type RecordedFrame struct {
+	Timestamp    string          `json:"ts"`
+	Direction    string          `json:"dir"`
+	ConnectionID string          `json:"conn"`
+	Size         int             `json:"size"`
+	Truncated    bool            `json:"truncated,omitempty"`
+	Frame        json.RawMessage `json:"frame,omitempty"`
+	Text         string          `json:"text,omitempty"` // Used when the frame is not valid JSON
+}
+
+// Recorder tees CDP frames into a timestamped, direction-tagged JSONL file
+type Recorder struct {
+	path          string
+	maxFrameBytes int
+	mu            sync.Mutex
+	file          *os.File
+	writer        *bufio.Writer
+	frames        int64
+}
+
+// NewRecorder creates a recorder writing to path. Frames larger than maxFrameBytes
+// have their large string fields truncated.
+func NewRecorder(path string, maxFrameBytes int) (*Recorder, error) {
+	if maxFrameBytes <= 0 {
+		maxFrameBytes = DefaultRecorderMaxFrameBytes
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CDP recording file: %v", err)
+	}
+
+	return &Recorder{
+		path:          path,
+		maxFrameBytes: maxFrameBytes,
+		file:          file,
+		writer:        bufio.NewWriter(file),
+	}, nil
+}
+
+// Path returns the location of the JSONL log
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Frames returns the number of frames recorded so far
+func (r *Recorder) Frames() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.frames
+}
+
+// Record appends a frame to the log. It is safe to call from multiple goroutines
+// and is a no-op on a nil or closed recorder.
+func (r *Recorder) Record(direction, connectionID string, message []byte) {
+	if r == nil {
+		return
+	}
+
+	entry := RecordedFrame{
+		Timestamp:    time.Now().UTC().Format(time.RFC3339Nano),
+		Direction:    direction,
+		ConnectionID: connectionID,
+		Size:         len(message),
+	}
+	entry.Frame, entry.Text, entry.Truncated = r.encodeFrame(message)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("CDP Recorder: Error encoding frame: %v", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.writer == nil {
+		return
+	}
+	r.writer.Write(line)
+	r.writer.WriteByte('\n')
+	r.frames++
+}
+
+// encodeFrame returns the frame as raw JSON, truncating oversized payloads
+func (r *Recorder) encodeFrame(message []byte) (json.RawMessage, string, bool) {
+	if !json.Valid(message) {
+		if len(message) > r.maxFrameBytes {
+			return nil, string(message[:r.maxFrameBytes]), true
+		}
+		return nil, string(message), false
+	}
+
+	if len(message) <= r.maxFrameBytes {
+		return json.RawMessage(message), "", false
+	}
+
+	// Large frames are almost always a single big string (screenshot data, response body)
+	var value interface{}
+	if err := json.Unmarshal(message, &value); err == nil {
+		if trimmed, err := json.Marshal(truncateStrings(value)); err == nil && len(trimmed) <= r.maxFrameBytes {
+			return json.RawMessage(trimmed), "", true
+		}
+	}
+
+	return nil, string(message[:r.maxFrameBytes]), true
+}
+
+// truncateStrings shortens every string longer than truncatedFieldBytes
+func truncateStrings(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if len(v) > truncatedFieldBytes {
+			return fmt.Sprintf("%s...[truncated %d bytes]", v[:truncatedFieldBytes], len(v)-truncatedFieldBytes)
+		}
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = truncateStrings(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = truncateStrings(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Close flushes and closes the log file. Further Record calls are ignored.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.writer == nil {
+		return nil
+	}
+
+	flushErr := r.writer.Flush()
+	closeErr := r.file.Close()
+	r.writer = nil
+
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
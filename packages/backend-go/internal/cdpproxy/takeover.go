@@ -0,0 +1,280 @@
+package cdpproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// takeoverTimeout returns control to automation if a human holds it this long, so a viewer
+// that leaves without releasing cannot stall the automation client
+const takeoverTimeout = 10 * time.Minute
+
+// Notifications sent to automation clients when a human takes or returns control.
+// They use the CDP event shape so clients that do not know them simply ignore them.
+const (
+	automationPausedEvent  = "Wallcrawler.automationPaused"
+	automationResumedEvent = "Wallcrawler.automationResumed"
+)
+
+// TakeoverState describes who currently controls the browser's input
+type TakeoverState struct {
+	Active    bool      `json:"active"`
+	ClientID  string    `json:"clientId,omitempty"`
+	StartedAt time.Time `json:"startedAt,omitempty"`
+}
+
+// takeoverInput is a mouse, wheel or keyboard event sent by the takeover page
+type takeoverInput struct {
+	Type       string  `json:"type"`  // takeover, release, mouse, key
+	Event      string  `json:"event"` // CDP event type, e.g. mousePressed, keyDown
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Button     string  `json:"button,omitempty"`
+	ClickCount int     `json:"clickCount,omitempty"`
+	DeltaX     float64 `json:"deltaX,omitempty"`
+	DeltaY     float64 `json:"deltaY,omitempty"`
+	Modifiers  int     `json:"modifiers,omitempty"`
+	Key        string  `json:"key,omitempty"`
+	Code       string  `json:"code,omitempty"`
+	Text       string  `json:"text,omitempty"`
+	KeyCode    int     `json:"keyCode,omitempty"`
+}
+
+// SetOnTakeoverChange sets the callback fired when a human takes or returns control
+func (p *CDPProxy) SetOnTakeoverChange(callback func(state TakeoverState)) {
+	p.onTakeoverChange = callback
+}
+
+// Takeover returns the current takeover state
+func (p *CDPProxy) Takeover() TakeoverState {
+	p.takeoverMutex.Lock()
+	defer p.takeoverMutex.Unlock()
+	return p.takeover
+}
+
+// beginTakeover gives input control to the client and pauses automation input
+func (p *CDPProxy) beginTakeover(client *ClientConnection) error {
+	if client.policy != nil && client.policy.viewOnly {
+		return fmt.Errorf("view-only access cannot take control")
+	}
+
+	p.takeoverMutex.Lock()
+	if p.takeover.Active {
+		owner := p.takeover.ClientID
+		p.takeoverMutex.Unlock()
+		if owner == client.ID {
+			return nil
+		}
+		return fmt.Errorf("browser is already controlled by %s", owner)
+	}
+
+	p.takeover = TakeoverState{
+		Active:    true,
+		ClientID:  client.ID,
+		StartedAt: time.Now(),
+	}
+	p.takeoverReleased = make(chan struct{})
+	p.takeoverTimer = time.AfterFunc(takeoverTimeout, func() {
+		log.Printf("CDP Proxy: Takeover by client %s timed out after %v", client.ID, takeoverTimeout)
+		p.endTakeover(client)
+	})
+	state := p.takeover
+	p.takeoverMutex.Unlock()
+
+	log.Printf("CDP Proxy: Client %s took control; automation input paused", client.ID)
+	p.notifyAutomationClients(client.ID, automationPausedEvent, state)
+	if p.onTakeoverChange != nil {
+		go p.onTakeoverChange(state)
+	}
+	return nil
+}
+
+// endTakeover returns control to automation if the client currently holds it
+func (p *CDPProxy) endTakeover(client *ClientConnection) {
+	p.takeoverMutex.Lock()
+	if !p.takeover.Active || p.takeover.ClientID != client.ID {
+		p.takeoverMutex.Unlock()
+		return
+	}
+
+	started := p.takeover.StartedAt
+	p.takeover = TakeoverState{}
+	close(p.takeoverReleased)
+	if p.takeoverTimer != nil {
+		p.takeoverTimer.Stop()
+		p.takeoverTimer = nil
+	}
+	state := p.takeover
+	p.takeoverMutex.Unlock()
+
+	log.Printf("CDP Proxy: Client %s released control after %v; automation input resumed",
+		client.ID, time.Since(started).Round(time.Second))
+	p.notifyAutomationClients(client.ID, automationResumedEvent, state)
+	if p.onTakeoverChange != nil {
+		go p.onTakeoverChange(state)
+	}
+}
+
+// heldInput queues one automation connection's Input.* commands while a human is in
+// control. Only input waits; the connection's other commands keep flowing to Chrome.
+type heldInput struct {
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+// holdAutomationInput reports whether an automation client's command was queued instead of
+// sent. Input.* commands are queued while another client holds control, and behind any
+// input already queued so the order is kept. The queue is flushed through forward once
+// control returns, or dropped when ctx (the connection) ends.
+func (p *CDPProxy) holdAutomationInput(ctx context.Context, client *ClientConnection, held *heldInput, method string, message []byte, forward func([]byte) error) bool {
+	if !strings.HasPrefix(method, "Input.") {
+		return false
+	}
+
+	held.mu.Lock()
+	defer held.mu.Unlock()
+
+	if len(held.frames) > 0 {
+		held.frames = append(held.frames, message)
+		return true
+	}
+
+	p.takeoverMutex.Lock()
+	if !p.takeover.Active || p.takeover.ClientID == client.ID {
+		p.takeoverMutex.Unlock()
+		return false
+	}
+	released := p.takeoverReleased
+	p.takeoverMutex.Unlock()
+
+	log.Printf("CDP Proxy: Holding %s from client %s during human takeover", method, client.ID)
+	held.frames = append(held.frames, message)
+	go p.flushHeldInput(ctx, client, held, released, forward)
+	return true
+}
+
+// flushHeldInput waits for the takeover to end and sends the queued input in order
+func (p *CDPProxy) flushHeldInput(ctx context.Context, client *ClientConnection, held *heldInput, released <-chan struct{}, forward func([]byte) error) {
+	select {
+	case <-released:
+	case <-ctx.Done():
+		held.mu.Lock()
+		dropped := len(held.frames)
+		held.frames = nil
+		held.mu.Unlock()
+		log.Printf("CDP Proxy: Dropped %d held input commands from client %s after it disconnected", dropped, client.ID)
+		return
+	}
+
+	// Holding the lock keeps new input behind the queue until it is drained
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	for _, frame := range held.frames {
+		if err := forward(frame); err != nil {
+			log.Printf("CDP Proxy: Error sending held input from client %s: %v", client.ID, err)
+			break
+		}
+	}
+	held.frames = nil
+}
+
+// notifyAutomationClients sends a takeover notification to every client except the one in control
+func (p *CDPProxy) notifyAutomationClients(excludeID, method string, state TakeoverState) {
+	params, _ := json.Marshal(state)
+	frame, _ := json.Marshal(cdpMessage{Method: method, Params: params})
+
+	p.connectionMutex.RLock()
+	defer p.connectionMutex.RUnlock()
+
+	for _, client := range p.clients {
+		if client.ID == excludeID || client.notify == nil {
+			continue
+		}
+		if err := client.notify(frame); err != nil {
+			log.Printf("CDP Proxy: Failed to notify client %s of takeover: %v", client.ID, err)
+		}
+	}
+}
+
+// handleTakeoverMessage applies a control or input message sent from the takeover page
+func (s *liveSession) handleTakeoverMessage(data []byte) error {
+	var input takeoverInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil
+	}
+
+	switch input.Type {
+	case "takeover":
+		if err := s.proxy.beginTakeover(s.client); err != nil {
+			return s.writeEvent(liveEvent{Type: "control", State: "denied", Reason: err.Error()})
+		}
+		return s.writeEvent(liveEvent{Type: "control", State: "granted"})
+	case "release":
+		s.proxy.endTakeover(s.client)
+		return s.writeEvent(liveEvent{Type: "control", State: "released"})
+	case "mouse", "key":
+		// Input is only forwarded while this viewer holds control
+		if state := s.proxy.Takeover(); !state.Active || state.ClientID != s.client.ID {
+			return nil
+		}
+		return s.dispatchInput(input)
+	}
+	return nil
+}
+
+// dispatchInput forwards a takeover input event to the page being streamed
+func (s *liveSession) dispatchInput(input takeoverInput) error {
+	s.stateMu.Lock()
+	sessionID := s.targetSessionID
+	s.stateMu.Unlock()
+
+	if sessionID == "" {
+		return nil
+	}
+
+	var method string
+	params := map[string]interface{}{
+		"type":      input.Event,
+		"modifiers": input.Modifiers,
+	}
+
+	switch input.Type {
+	case "mouse":
+		method = "Input.dispatchMouseEvent"
+		params["x"] = input.X
+		params["y"] = input.Y
+		params["button"] = input.Button
+		if params["button"] == "" {
+			params["button"] = "none"
+		}
+		params["clickCount"] = input.ClickCount
+		if input.Event == "mouseWheel" {
+			params["deltaX"] = input.DeltaX
+			params["deltaY"] = input.DeltaY
+		}
+	case "key":
+		method = "Input.dispatchKeyEvent"
+		params["key"] = input.Key
+		params["code"] = input.Code
+		params["windowsVirtualKeyCode"] = input.KeyCode
+		if input.Text != "" {
+			params["text"] = input.Text
+		}
+	}
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.send(cdpMessage{
+		ID:        s.nextCommandID(),
+		Method:    method,
+		Params:    rawParams,
+		SessionID: sessionID,
+	})
+}
@@ -0,0 +1,79 @@
+package cdpproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// uploadFetchTimeout bounds how long the controller may take to pull a staged upload
+const uploadFetchTimeout = 2 * time.Minute
+
+// UploadRequest asks the controller to pull a file staged in S3 into the session
+type UploadRequest struct {
+	UploadID string `json:"uploadId"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+type uploadResponse struct {
+	Path string `json:"path"`
+}
+
+// SetUploadHandler sets the function that fetches a staged upload and returns its
+// path in the container. Without a handler the internal uploads endpoint returns 404.
+func (p *CDPProxy) SetUploadHandler(handler func(ctx context.Context, req UploadRequest) (string, error)) {
+	p.uploadHandler = handler
+}
+
+// handleInternalUpload serves POST /internal/uploads. Only tokens minted by the API
+// with the internal scope are accepted.
+func (p *CDPProxy) handleInternalUpload(w http.ResponseWriter, r *http.Request) {
+	payload, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if payload.Scope != utils.CDPTokenScopeInternal {
+		p.rejectRequest(w, r, http.StatusForbidden, "Forbidden: internal endpoint", fmt.Errorf("signing key scope %q cannot call internal endpoints", payload.Scope), payload)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if p.uploadHandler == nil {
+		http.Error(w, "Uploads not supported", http.StatusNotFound)
+		return
+	}
+
+	var req UploadRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		http.Error(w, "Invalid upload request", http.StatusBadRequest)
+		return
+	}
+	if req.UploadID == "" || req.Filename == "" {
+		http.Error(w, "uploadId and filename are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), uploadFetchTimeout)
+	defer cancel()
+
+	path, err := p.uploadHandler(ctx, req)
+	if err != nil {
+		log.Printf("CDP Proxy: Failed to fetch upload %s: %v", req.UploadID, err)
+		http.Error(w, "Failed to fetch upload", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(uploadResponse{Path: path}); err != nil {
+		log.Printf("CDP Proxy: Error writing upload response: %v", err)
+	}
+}
@@ -0,0 +1,422 @@
+package forwardproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wallcrawler/backend-go/internal/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+const (
+	dialTimeout = 15 * time.Second
+
+	// Upstream failures are reported at most once per interval for each upstream
+	failureReportInterval = time.Minute
+)
+
+// UpstreamError describes failures to reach a destination through an upstream proxy
+type UpstreamError struct {
+	Upstream string // Upstream server without credentials, "direct" when no proxy matched
+	Host     string // Destination the browser asked for
+	Err      error
+	Count    int // Failures since the last report, including this one
+}
+
+// upstream is a configured proxy server
+type upstream struct {
+	config    types.ProxyConfig
+	server    *url.URL
+	transport *http.Transport // Used for plain HTTP requests
+}
+
+// label identifies the upstream in logs and events without exposing credentials
+func (u *upstream) label() string {
+	if u == nil {
+		return "direct"
+	}
+	return u.server.Scheme + "://" + u.server.Host
+}
+
+// Proxy is a local HTTP proxy that Chrome is pointed at. It forwards each request
+// to the upstream selected for the request host, adding the upstream's credentials,
+// since Chrome cannot take proxy credentials on the command line.
+type Proxy struct {
+	rules  []*upstream // Upstreams with a domain pattern, matched in order
+	pool   []*upstream // Upstreams without a domain pattern, rotated per connection
+	next   uint64
+	direct *http.Transport
+
+	listener net.Listener
+	server   *http.Server
+
+	// Traffic totals across all connections
+	bytesIn  int64 // Browser -> upstream
+	bytesOut int64 // Upstream -> browser
+
+	onUpstreamError func(UpstreamError)
+	failureMutex    sync.Mutex
+	lastReported    map[string]time.Time
+	suppressed      map[string]int
+}
+
+// New creates a forwarding proxy for the session's upstream proxy configurations
+func New(configs []types.ProxyConfig) (*Proxy, error) {
+	if err := utils.ValidateSessionProxies(configs); err != nil {
+		return nil, err
+	}
+
+	p := &Proxy{
+		direct:       &http.Transport{DialContext: (&net.Dialer{Timeout: dialTimeout}).DialContext},
+		lastReported: make(map[string]time.Time),
+		suppressed:   make(map[string]int),
+	}
+
+	for _, config := range configs {
+		server, _ := url.Parse(config.Server)
+		u := &upstream{config: config, server: server}
+
+		// net/http speaks to HTTP, HTTPS and SOCKS5 proxies and sends the credentials itself
+		proxyURL := *server
+		if config.Username != "" {
+			proxyURL.User = url.UserPassword(config.Username, config.Password)
+		}
+		u.transport = &http.Transport{
+			Proxy:       http.ProxyURL(&proxyURL),
+			DialContext: (&net.Dialer{Timeout: dialTimeout}).DialContext,
+		}
+
+		if config.DomainPattern != "" {
+			p.rules = append(p.rules, u)
+		} else {
+			p.pool = append(p.pool, u)
+		}
+	}
+
+	return p, nil
+}
+
+// SetOnUpstreamError sets the callback fired when requests fail through an upstream
+func (p *Proxy) SetOnUpstreamError(callback func(UpstreamError)) {
+	p.onUpstreamError = callback
+}
+
+// TrafficTotals returns the bytes forwarded in each direction since the proxy started
+func (p *Proxy) TrafficTotals() (bytesIn, bytesOut int64) {
+	return atomic.LoadInt64(&p.bytesIn), atomic.LoadInt64(&p.bytesOut)
+}
+
+// Start listens on a loopback port and returns the address Chrome should use
+func (p *Proxy) Start() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen: %v", err)
+	}
+	p.listener = listener
+	p.server = &http.Server{Handler: p}
+
+	go func() {
+		if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Forward proxy server error: %v", err)
+		}
+	}()
+
+	log.Printf("Forward proxy listening on %s (%d routed, %d rotating upstreams)", listener.Addr(), len(p.rules), len(p.pool))
+	return listener.Addr().String(), nil
+}
+
+// Stop shuts down the proxy and closes open tunnels
+func (p *Proxy) Stop() error {
+	if p.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.server.Shutdown(ctx); err != nil {
+		return p.server.Close()
+	}
+	return nil
+}
+
+// pick returns the upstream for a host: the first matching domain rule, otherwise
+// the next upstream in the rotation, or nil to connect directly.
+func (p *Proxy) pick(host string) *upstream {
+	for _, u := range p.rules {
+		if utils.MatchProxyDomain(u.config.DomainPattern, host) {
+			return u
+		}
+	}
+	if len(p.pool) == 0 {
+		return nil
+	}
+	n := atomic.AddUint64(&p.next, 1)
+	return p.pool[(n-1)%uint64(len(p.pool))]
+}
+
+// ServeHTTP handles CONNECT tunnels and absolute-URI HTTP requests from Chrome
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.handleConnect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "Bad Request: not a proxy request", http.StatusBadRequest)
+		return
+	}
+	p.handleForward(w, r)
+}
+
+// handleConnect opens a tunnel to the destination through the selected upstream
+func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
+	up := p.pick(r.Host)
+
+	ctx, cancel := context.WithTimeout(r.Context(), dialTimeout)
+	upstreamConn, err := p.dial(ctx, up, r.Host)
+	cancel()
+	if err != nil {
+		p.reportFailure(up, r.Host, err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstreamConn.Close()
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		upstreamConn.Close()
+		return
+	}
+
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		clientConn.Close()
+		upstreamConn.Close()
+		return
+	}
+
+	// Bytes the browser sent after the CONNECT line may already be buffered
+	var clientReader io.Reader = clientConn
+	if clientBuf.Reader.Buffered() > 0 {
+		clientReader = io.MultiReader(clientBuf.Reader, clientConn)
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		copyCounted(upstreamConn, clientReader, &p.bytesIn)
+		closeWrite(upstreamConn)
+		done <- struct{}{}
+	}()
+	go func() {
+		copyCounted(clientConn, upstreamConn, &p.bytesOut)
+		closeWrite(clientConn)
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+
+	clientConn.Close()
+	upstreamConn.Close()
+}
+
+// handleForward proxies a plain HTTP request through the selected upstream
+func (p *Proxy) handleForward(w http.ResponseWriter, r *http.Request) {
+	up := p.pick(r.URL.Host)
+	transport := p.direct
+	if up != nil {
+		transport = up.transport
+	}
+
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+	outReq.Header.Del("Proxy-Connection")
+	outReq.Header.Del("Proxy-Authorization")
+	if r.Body != nil {
+		outReq.Body = &countingReader{r: r.Body, n: &p.bytesIn}
+	}
+
+	resp, err := transport.RoundTrip(outReq)
+	if err != nil {
+		p.reportFailure(up, r.URL.Host, err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	copyCounted(w, resp.Body, &p.bytesOut)
+}
+
+// dial connects to addr through the upstream, or directly when upstream is nil
+func (p *Proxy) dial(ctx context.Context, up *upstream, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if up == nil {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", up.server.Host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	switch up.server.Scheme {
+	case "https":
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: up.server.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with upstream failed: %v", err)
+		}
+		conn = tlsConn
+		fallthrough
+	case "http":
+		var tunnel net.Conn
+		if tunnel, err = httpConnect(conn, addr, up.config.Username, up.config.Password); err == nil {
+			conn = tunnel
+		}
+	case "socks5":
+		err = socks5Connect(conn, addr, up.config.Username, up.config.Password)
+	default:
+		err = fmt.Errorf("unsupported upstream scheme %q", up.server.Scheme)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// httpConnect asks an HTTP proxy to open a tunnel to addr
+func httpConnect(conn net.Conn, addr, username, password string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("reading upstream CONNECT response: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream refused CONNECT: %s", resp.Status)
+	}
+
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// reportFailure logs an upstream failure and reports it through the callback,
+// at most once per interval for each upstream
+func (p *Proxy) reportFailure(up *upstream, host string, err error) {
+	label := up.label()
+	log.Printf("Forward proxy: %s via %s failed: %v", host, label, err)
+
+	p.failureMutex.Lock()
+	p.suppressed[label]++
+	if time.Since(p.lastReported[label]) < failureReportInterval {
+		p.failureMutex.Unlock()
+		return
+	}
+	count := p.suppressed[label]
+	p.suppressed[label] = 0
+	p.lastReported[label] = time.Now()
+	p.failureMutex.Unlock()
+
+	if p.onUpstreamError != nil {
+		go p.onUpstreamError(UpstreamError{Upstream: label, Host: host, Err: err, Count: count})
+	}
+}
+
+// copyCounted copies src to dst and adds the bytes written to counter
+func copyCounted(dst io.Writer, src io.Reader, counter *int64) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			written, werr := dst.Write(buf[:n])
+			atomic.AddInt64(counter, int64(written))
+			if werr != nil {
+				return
+			}
+			if f, ok := dst.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// closeWrite half-closes a connection so the peer sees EOF
+func closeWrite(conn net.Conn) {
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		c.CloseWrite()
+		return
+	}
+	conn.Close()
+}
+
+// countingReader counts bytes read from a request body
+type countingReader struct {
+	r io.ReadCloser
+	n *int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.r.Close()
+}
+
+// bufferedConn is a connection whose first bytes were already read into a buffer
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
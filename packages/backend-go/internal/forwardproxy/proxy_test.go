@@ -0,0 +1,422 @@
+package forwardproxy
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wallcrawler/backend-go/internal/types"
+	"github.com/wallcrawler/backend-go/internal/utils"
+)
+
+// fakeUpstream is an HTTP or SOCKS5 proxy server that records what the forward proxy sent
+// it. Tunnels it opens echo everything back instead of reaching the destination.
+type fakeUpstream struct {
+	listener net.Listener
+	username string
+	password string
+
+	mu          sync.Mutex
+	auth        []string // Proxy-Authorization headers, or SOCKS5 "user:pass"
+	destination []string // CONNECT targets and forwarded URLs
+}
+
+func newFakeUpstream(t *testing.T, socks5 bool, username, password string) *fakeUpstream {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &fakeUpstream{listener: listener, username: username, password: password}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if socks5 {
+				go u.serveSOCKS5(conn)
+			} else {
+				go u.serveHTTP(conn)
+			}
+		}
+	}()
+	return u
+}
+
+// server returns the upstream's proxy URL for the given scheme
+func (u *fakeUpstream) server(scheme string) string {
+	return scheme + "://" + u.listener.Addr().String()
+}
+
+func (u *fakeUpstream) record(auth, destination string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.auth = append(u.auth, auth)
+	u.destination = append(u.destination, destination)
+}
+
+func (u *fakeUpstream) seen() ([]string, []string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.auth...), append([]string(nil), u.destination...)
+}
+
+func (u *fakeUpstream) serveHTTP(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	req, err := http.ReadRequest(reader)
+	if err != nil {
+		return
+	}
+
+	auth := req.Header.Get("Proxy-Authorization")
+	destination := req.RequestURI
+	u.record(auth, destination)
+
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(u.username+":"+u.password))
+	if u.username != "" && auth != want {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+
+	if req.Method != http.MethodConnect {
+		body := "proxied " + destination
+		fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+		return
+	}
+
+	io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+	io.Copy(conn, reader)
+}
+
+func (u *fakeUpstream) serveSOCKS5(conn net.Conn) {
+	defer conn.Close()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+
+	auth := ""
+	if u.username != "" {
+		conn.Write([]byte{socks5Version, socks5AuthPassword})
+
+		version := make([]byte, 2)
+		if _, err := io.ReadFull(conn, version); err != nil {
+			return
+		}
+		username := make([]byte, version[1])
+		io.ReadFull(conn, username)
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		password := make([]byte, length[0])
+		io.ReadFull(conn, password)
+
+		auth = string(username) + ":" + string(password)
+		if auth != u.username+":"+u.password {
+			u.record(auth, "")
+			conn.Write([]byte{socks5PasswordAuthV1, 0x01})
+			return
+		}
+		conn.Write([]byte{socks5PasswordAuthV1, 0x00})
+	} else {
+		conn.Write([]byte{socks5Version, socks5AuthNone})
+	}
+
+	request := make([]byte, 5)
+	if _, err := io.ReadFull(conn, request); err != nil || request[3] != socks5AddrDomain {
+		return
+	}
+	host := make([]byte, request[4])
+	io.ReadFull(conn, host)
+	port := make([]byte, 2)
+	io.ReadFull(conn, port)
+	u.record(auth, net.JoinHostPort(string(host), strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+
+	conn.Write([]byte{socks5Version, 0x00, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	io.Copy(conn, conn)
+}
+
+// newEchoServer accepts TCP connections and echoes what it receives
+func newEchoServer(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func startProxy(t *testing.T, configs ...types.ProxyConfig) (*Proxy, string) {
+	t.Helper()
+
+	proxy, err := New(configs)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	addr, err := proxy.Start()
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { proxy.Stop() })
+	return proxy, addr
+}
+
+// connectThrough opens a CONNECT tunnel to target through the proxy, as Chrome does
+func connectThrough(t *testing.T, proxyAddr, target string) (net.Conn, int) {
+	t.Helper()
+
+	conn, err := net.DialTimeout("tcp", proxyAddr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", target, target)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatalf("read CONNECT response: %v", err)
+	}
+	resp.Body.Close()
+	return conn, resp.StatusCode
+}
+
+// assertEcho sends a message through the tunnel and expects it back
+func assertEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	if _, err := io.WriteString(conn, "ping"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(reply) != "ping" {
+		t.Errorf("tunnel returned %q, want %q", reply, "ping")
+	}
+}
+
+func TestConnectDirect(t *testing.T) {
+	echoAddr := newEchoServer(t)
+	proxy, addr := startProxy(t)
+
+	conn, status := connectThrough(t, addr, echoAddr)
+	if status != http.StatusOK {
+		t.Fatalf("CONNECT status = %d, want 200", status)
+	}
+	assertEcho(t, conn)
+	conn.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		bytesIn, bytesOut := proxy.TrafficTotals()
+		if bytesIn == 4 && bytesOut == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("TrafficTotals() = (%d, %d), want (4, 4)", bytesIn, bytesOut)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConnectThroughUpstream(t *testing.T) {
+	tests := []struct {
+		name       string
+		socks5     bool
+		scheme     string
+		password   string // Sent by the forward proxy; the upstream expects "secret"
+		wantStatus int
+		wantAuth   string
+	}{
+		{
+			name:       "http upstream",
+			scheme:     "http",
+			password:   "secret",
+			wantStatus: http.StatusOK,
+			wantAuth:   "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret")),
+		},
+		{
+			name:       "http upstream refusing credentials",
+			scheme:     "http",
+			password:   "wrong",
+			wantStatus: http.StatusBadGateway,
+			wantAuth:   "Basic " + base64.StdEncoding.EncodeToString([]byte("user:wrong")),
+		},
+		{
+			name:       "socks5 upstream",
+			socks5:     true,
+			scheme:     "socks5",
+			password:   "secret",
+			wantStatus: http.StatusOK,
+			wantAuth:   "user:secret",
+		},
+		{
+			name:       "socks5 upstream refusing credentials",
+			socks5:     true,
+			scheme:     "socks5",
+			password:   "wrong",
+			wantStatus: http.StatusBadGateway,
+			wantAuth:   "user:wrong",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newFakeUpstream(t, tt.socks5, "user", "secret")
+			proxy, addr := startProxy(t, types.ProxyConfig{
+				Type:     utils.ProxyTypeExternal,
+				Server:   upstream.server(tt.scheme),
+				Username: "user",
+				Password: tt.password,
+			})
+
+			failures := make(chan UpstreamError, 1)
+			proxy.SetOnUpstreamError(func(e UpstreamError) { failures <- e })
+
+			conn, status := connectThrough(t, addr, "site.test:443")
+			if status != tt.wantStatus {
+				t.Fatalf("CONNECT status = %d, want %d", status, tt.wantStatus)
+			}
+			if status == http.StatusOK {
+				assertEcho(t, conn)
+			}
+
+			auth, destinations := upstream.seen()
+			if len(auth) != 1 || auth[0] != tt.wantAuth {
+				t.Errorf("upstream saw credentials %q, want [%q]", auth, tt.wantAuth)
+			}
+			if status == http.StatusOK && (len(destinations) != 1 || destinations[0] != "site.test:443") {
+				t.Errorf("upstream saw destinations %q, want [site.test:443]", destinations)
+			}
+
+			if status != http.StatusBadGateway {
+				return
+			}
+			select {
+			case failure := <-failures:
+				if failure.Upstream != upstream.server(tt.scheme) || failure.Host != "site.test:443" {
+					t.Errorf("failure = %+v, want %s for site.test:443", failure, upstream.server(tt.scheme))
+				}
+			case <-time.After(time.Second):
+				t.Error("upstream failure was not reported")
+			}
+		})
+	}
+}
+
+func TestForwardReplacesBrowserCredentials(t *testing.T) {
+	upstream := newFakeUpstream(t, false, "user", "secret")
+	_, addr := startProxy(t, types.ProxyConfig{
+		Type:     utils.ProxyTypeExternal,
+		Server:   upstream.server("http"),
+		Username: "user",
+		Password: "secret",
+	})
+
+	// The browser's own proxy credentials must not reach the upstream
+	proxyURL := &url.URL{Scheme: "http", Host: addr, User: url.UserPassword("browser", "leak")}
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}, Timeout: 5 * time.Second}
+
+	resp, err := client.Get("http://site.test/page?q=1")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "proxied http://site.test/page?q=1" {
+		t.Fatalf("response = %d %q, want the upstream's reply", resp.StatusCode, body)
+	}
+	auth, _ := upstream.seen()
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	if len(auth) != 1 || auth[0] != want {
+		t.Errorf("upstream saw credentials %q, want [%q]", auth, want)
+	}
+}
+
+func TestServeHTTPRejectsNonProxyRequests(t *testing.T) {
+	proxy, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/json/version", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestPick(t *testing.T) {
+	config := func(server, pattern string) types.ProxyConfig {
+		return types.ProxyConfig{Type: utils.ProxyTypeExternal, Server: server, DomainPattern: pattern}
+	}
+	proxy, err := New([]types.ProxyConfig{
+		config("http://rotate-a.test:8080", ""),
+		config("http://example.test:8080", "*.example.com"),
+		config("http://rotate-b.test:8080", ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"www.example.com:443", "http://example.test:8080"},
+		{"example.com:443", "http://example.test:8080"},
+		{"other.test:443", "http://rotate-a.test:8080"},
+		{"other.test:443", "http://rotate-b.test:8080"},
+		{"other.test:443", "http://rotate-a.test:8080"},
+	}
+	for _, tt := range tests {
+		if got := proxy.pick(tt.host).label(); got != tt.want {
+			t.Errorf("pick(%q) = %s, want %s", tt.host, got, tt.want)
+		}
+	}
+
+	direct, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := direct.pick("site.test:443"); got != nil {
+		t.Errorf("pick() without upstreams = %s, want direct", got.label())
+	}
+}
@@ -0,0 +1,142 @@
+package forwardproxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// SOCKS5 protocol constants (RFC 1928, RFC 1929)
+const (
+	socks5Version        = 0x05
+	socks5AuthNone       = 0x00
+	socks5AuthPassword   = 0x02
+	socks5AuthNoMatch    = 0xff
+	socks5PasswordAuthV1 = 0x01
+	socks5CmdConnect     = 0x01
+	socks5AddrIPv4       = 0x01
+	socks5AddrDomain     = 0x03
+	socks5AddrIPv6       = 0x04
+)
+
+var socks5ReplyErrors = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socks5Connect asks a SOCKS5 server to open a connection to addr over conn
+func socks5Connect(conn net.Conn, addr, username, password string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid destination %q: %v", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid destination port %q", portStr)
+	}
+
+	// Method negotiation
+	methods := []byte{socks5AuthNone}
+	if username != "" {
+		methods = append(methods, socks5AuthPassword)
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("reading SOCKS5 method reply: %v", err)
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("upstream is not a SOCKS5 server")
+	}
+
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if username == "" {
+			return fmt.Errorf("SOCKS5 upstream requires credentials")
+		}
+		auth := []byte{socks5PasswordAuthV1, byte(len(username))}
+		auth = append(auth, username...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return fmt.Errorf("reading SOCKS5 auth reply: %v", err)
+		}
+		if reply[1] != 0x00 {
+			return fmt.Errorf("SOCKS5 authentication failed")
+		}
+	case socks5AuthNoMatch:
+		return fmt.Errorf("SOCKS5 upstream accepted none of the offered authentication methods")
+	default:
+		return fmt.Errorf("SOCKS5 upstream chose unsupported authentication method %d", reply[1])
+	}
+
+	// Connect request
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5AddrIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5AddrIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("destination host too long")
+		}
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("reading SOCKS5 connect reply: %v", err)
+	}
+	if header[1] != 0x00 {
+		if msg, ok := socks5ReplyErrors[header[1]]; ok {
+			return fmt.Errorf("SOCKS5 connect to %s failed: %s", addr, msg)
+		}
+		return fmt.Errorf("SOCKS5 connect to %s failed with code %d", addr, header[1])
+	}
+
+	// Skip the bound address, which is not needed for a tunnel
+	var skip int
+	switch header[3] {
+	case socks5AddrIPv4:
+		skip = net.IPv4len + 2
+	case socks5AddrIPv6:
+		skip = net.IPv6len + 2
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		skip = int(length[0]) + 2
+	default:
+		return fmt.Errorf("SOCKS5 reply has unknown address type %d", header[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip)); err != nil {
+		return fmt.Errorf("reading SOCKS5 bound address: %v", err)
+	}
+
+	return nil
+}
@@ -213,16 +213,25 @@ type SessionState struct {
 	StartedAt      string                 `json:"startedAt"`
 	Status         string                 `json:"status"` // RUNNING, ERROR, TIMED_OUT, COMPLETED
 	UpdatedAt      string                 `json:"updatedAt"`
-	AvgCPUUsage    *int                   `json:"avgCpuUsage,omitempty"`
+	AvgCPUUsage    *int                   `json:"avgCpuUsage,omitempty"` // Average browser CPU, percent of one core
 	ContextID      *string                `json:"contextId,omitempty"`
 	ContextPersist bool                   `json:"contextPersist,omitempty"`
 	EndedAt        *string                `json:"endedAt,omitempty"`
-	MemoryUsage    *int                   `json:"memoryUsage,omitempty"`
+	MemoryUsage    *int                   `json:"memoryUsage,omitempty"` // Peak browser memory (MB)
 	UserMetadata   map[string]interface{} `json:"userMetadata,omitempty"`
 
 	// Internal lifecycle tracking (not exposed directly to SDK)
 	InternalStatus    string  `json:"-" dynamodbav:"internalStatus,omitempty"`
+	Version           int64   `json:"-" dynamodbav:"version,omitempty"` // Incremented by every write to the record
 	ContextStorageKey *string `json:"-" dynamodbav:"contextStorageKey,omitempty"`
+	RecordCDP         bool    `json:"-" dynamodbav:"recordCdp,omitempty"`
+	RecordSession     bool    `json:"-" dynamodbav:"recordSession,omitempty"`
+	RecordScreencast  bool    `json:"-" dynamodbav:"recordScreencast,omitempty"`
+	ScreencastFPS     float64 `json:"-" dynamodbav:"screencastFps,omitempty"`
+
+	BrowserSettings *BrowserSettings `json:"-" dynamodbav:"browserSettings,omitempty"`
+	Proxies         []ProxyConfig    `json:"-" dynamodbav:"-"` // Passed to the task only; credentials are never stored
+	CDPPolicy       *CDPPolicy       `json:"-" dynamodbav:"-"` // Project policy, passed to the task as CDP_POLICY
 
 	// Additional fields for session creation response
 	ConnectURL        *string `json:"connectUrl,omitempty"`
@@ -230,9 +239,11 @@ type SessionState struct {
 	SigningKey        *string `json:"signingKey,omitempty"`
 
 	// Internal fields (not exposed in SDK)
-	ECSTaskARN  string       `json:"ecsTaskArn,omitempty"`
-	PublicIP    string       `json:"publicIP,omitempty"`
-	ModelConfig *ModelConfig `json:"modelConfig,omitempty"`
+	ECSTaskARN    string       `json:"ecsTaskArn,omitempty"`
+	PublicIP      string       `json:"publicIP,omitempty"`
+	ModelConfig   *ModelConfig `json:"modelConfig,omitempty"`
+	FailureReason *string      `json:"failureReason,omitempty"` // Why the session ended as FAILED
+	TaskStoppedAt *string      `json:"taskStoppedAt,omitempty"` // When ECS reported the task stopped
 
 	// EventBridge Integration
 	EventHistory       []SessionEvent `json:"eventHistory,omitempty"`
@@ -258,21 +269,98 @@ type SessionEvent struct {
 	CorrelationID string                 `json:"correlationId,omitempty"`
 }
 
+// Session log entry types
+const (
+	SessionLogTypeConsole   = "console"   // Runtime.consoleAPICalled
+	SessionLogTypeException = "exception" // Runtime.exceptionThrown
+	SessionLogTypeBrowser   = "browser"   // Log.entryAdded (interventions, violations, network errors)
+	SessionLogTypeNetwork   = "network"   // Network.requestWillBeSent, responseReceived and loadingFailed
+)
+
+// Session log entry levels
+const (
+	SessionLogLevelDebug   = "debug"
+	SessionLogLevelInfo    = "info"
+	SessionLogLevelWarning = "warning"
+	SessionLogLevelError   = "error"
+)
+
+// SessionLogEntry is one page event captured by the controller and stored in the session logs table
+type SessionLogEntry struct {
+	SessionID    string `json:"-" dynamodbav:"sessionId"`
+	LogID        string `json:"id" dynamodbav:"logId"` // Sort key, ordered by time
+	ProjectID    string `json:"-" dynamodbav:"projectId"`
+	Timestamp    int64  `json:"timestamp" dynamodbav:"ts"` // Unix milliseconds
+	Type         string `json:"type" dynamodbav:"type"`
+	Level        string `json:"level" dynamodbav:"level"`
+	Message      string `json:"message,omitempty" dynamodbav:"msg,omitempty"`
+	PageID       string `json:"pageId,omitempty" dynamodbav:"pageId,omitempty"`
+	URL          string `json:"url,omitempty" dynamodbav:"url,omitempty"`
+	Method       string `json:"method,omitempty" dynamodbav:"method,omitempty"`
+	Status       int    `json:"status,omitempty" dynamodbav:"status,omitempty"`
+	RequestID    string `json:"requestId,omitempty" dynamodbav:"requestId,omitempty"`
+	ResourceType string `json:"resourceType,omitempty" dynamodbav:"resourceType,omitempty"`
+	Source       string `json:"source,omitempty" dynamodbav:"source,omitempty"` // Script location, or the Log domain source
+	ExpiresAt    int64  `json:"-" dynamodbav:"expiresAt"`                       // TTL
+}
+
 // ResourceLimits defines session resource constraints
 type ResourceLimits struct {
-	MaxCPU      int `json:"maxCPU"`      // Maximum CPU allocation
-	MaxMemory   int `json:"maxMemory"`   // Maximum memory (MB)
-	MaxDuration int `json:"maxDuration"` // Maximum session duration (seconds)
-	MaxActions  int `json:"maxActions"`  // Maximum actions per session
+	MaxCPU      int `json:"maxCPU" dynamodbav:"maxCPU"`           // Maximum CPU allocation
+	MaxMemory   int `json:"maxMemory" dynamodbav:"maxMemory"`     // Maximum memory (MB)
+	MaxDuration int `json:"maxDuration" dynamodbav:"maxDuration"` // Maximum session duration (seconds)
+	MaxActions  int `json:"maxActions" dynamodbav:"maxActions"`   // Maximum Input.* commands per session, 0 = unlimited
 }
 
 // BillingInfo tracks usage for cost allocation
 type BillingInfo struct {
-	CostCenter    string    `json:"costCenter,omitempty"`
-	CPUSeconds    float64   `json:"cpuSeconds"`
-	MemoryMBHours float64   `json:"memoryMBHours"`
-	ActionsCount  int       `json:"actionsCount"`
-	LastBillingAt time.Time `json:"lastBillingAt"`
+	CostCenter    string    `json:"costCenter,omitempty" dynamodbav:"costCenter,omitempty"`
+	CPUSeconds    float64   `json:"cpuSeconds" dynamodbav:"cpuSeconds"`
+	MemoryMBHours float64   `json:"memoryMBHours" dynamodbav:"memoryMBHours"`
+	ActionsCount  int       `json:"actionsCount" dynamodbav:"actionsCount"`
+	LastBillingAt time.Time `json:"lastBillingAt" dynamodbav:"lastBillingAt"`
+}
+
+// CDPPolicy restricts which CDP methods a client may send through the proxy.
+// Patterns are exact method names ("Browser.close"), whole domains ("SystemInfo.*") or "*".
+type CDPPolicy struct {
+	Allow    []string `json:"allow,omitempty" dynamodbav:"allow,omitempty"`       // If set, only matching methods are allowed
+	Deny     []string `json:"deny,omitempty" dynamodbav:"deny,omitempty"`         // Matching methods are always refused
+	ReadOnly bool     `json:"readOnly,omitempty" dynamodbav:"readOnly,omitempty"` // Observer mode: no input or script evaluation
+}
+
+// BrowserSettings controls how the session's Chrome is launched and emulated
+type BrowserSettings struct {
+	Viewport          *Viewport    `json:"viewport,omitempty" dynamodbav:"viewport,omitempty"`
+	DeviceScaleFactor float64      `json:"deviceScaleFactor,omitempty" dynamodbav:"deviceScaleFactor,omitempty"`
+	UserAgent         string       `json:"userAgent,omitempty" dynamodbav:"userAgent,omitempty"`
+	Locale            string       `json:"locale,omitempty" dynamodbav:"locale,omitempty"`     // BCP 47 tag, also sent as Accept-Language
+	Timezone          string       `json:"timezone,omitempty" dynamodbav:"timezone,omitempty"` // IANA name, e.g. Europe/Berlin
+	Geolocation       *Geolocation `json:"geolocation,omitempty" dynamodbav:"geolocation,omitempty"`
+	Headful           bool         `json:"headful,omitempty" dynamodbav:"headful,omitempty"` // Run a headed Chrome under Xvfb
+	BlockAds          bool         `json:"blockAds,omitempty" dynamodbav:"blockAds,omitempty"`
+}
+
+// Viewport is the page size in CSS pixels
+type Viewport struct {
+	Width  int `json:"width" dynamodbav:"width"`
+	Height int `json:"height" dynamodbav:"height"`
+}
+
+// Geolocation is the position reported to pages through the Geolocation API
+type Geolocation struct {
+	Latitude  float64 `json:"latitude" dynamodbav:"latitude"`
+	Longitude float64 `json:"longitude" dynamodbav:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty" dynamodbav:"accuracy,omitempty"` // Meters
+}
+
+// ProxyConfig is an upstream proxy the session's browser traffic is routed through
+type ProxyConfig struct {
+	Type          string `json:"type"`               // Only "external" is supported
+	Server        string `json:"server"`             // http://, https:// or socks5:// host:port
+	Username      string `json:"username,omitempty"` // Optional proxy credentials
+	Password      string `json:"password,omitempty"`
+	DomainPattern string `json:"domainPattern,omitempty"` // Route only matching hosts, e.g. "*.example.com"
 }
 
 type ModelConfig struct {
@@ -309,13 +397,16 @@ const (
 )
 
 type Project struct {
-	ID             string  `json:"id" dynamodbav:"projectId"`
-	Name           string  `json:"name" dynamodbav:"name"`
-	OwnerID        *string `json:"ownerId,omitempty" dynamodbav:"ownerId,omitempty"`
-	DefaultTimeout int     `json:"defaultTimeout" dynamodbav:"defaultTimeout"`
-	Concurrency    int     `json:"concurrency" dynamodbav:"concurrency"`
-	Status         string  `json:"status" dynamodbav:"status"`
-	CreatedAt      string  `json:"createdAt" dynamodbav:"createdAt"`
-	UpdatedAt      string  `json:"updatedAt" dynamodbav:"updatedAt"`
-	BillingTier    *string `json:"billingTier,omitempty" dynamodbav:"billingTier,omitempty"`
+	ID             string     `json:"id" dynamodbav:"projectId"`
+	Name           string     `json:"name" dynamodbav:"name"`
+	OwnerID        *string    `json:"ownerId,omitempty" dynamodbav:"ownerId,omitempty"`
+	DefaultTimeout int        `json:"defaultTimeout" dynamodbav:"defaultTimeout"`
+	Concurrency    int        `json:"concurrency" dynamodbav:"concurrency"`
+	Status         string     `json:"status" dynamodbav:"status"`
+	CreatedAt      string     `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt      string     `json:"updatedAt" dynamodbav:"updatedAt"`
+	BillingTier    *string    `json:"billingTier,omitempty" dynamodbav:"billingTier,omitempty"`
+	CDPPolicy      *CDPPolicy `json:"cdpPolicy,omitempty" dynamodbav:"cdpPolicy,omitempty"`
+	MaxUploadBytes int64      `json:"maxUploadBytes,omitempty" dynamodbav:"maxUploadBytes,omitempty"` // Per-file session upload cap, 0 = default
+	MaxActions     int        `json:"maxActions,omitempty" dynamodbav:"maxActions,omitempty"`         // Per-session Input.* command cap, 0 = default, negative = unlimited
 }
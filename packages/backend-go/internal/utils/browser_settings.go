@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata" // Lambda images do not ship a zoneinfo database
+
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+// Browser setting bounds
+const (
+	minViewportWidth     = 320
+	maxViewportWidth     = 3840
+	minViewportHeight    = 240
+	maxViewportHeight    = 2160
+	minDeviceScaleFactor = 0.5
+	maxDeviceScaleFactor = 4
+	maxUserAgentLength   = 512
+)
+
+// Screencast capture rate bounds, in frames per second
+const (
+	DefaultScreencastFPS = 1.0
+	MaxScreencastFPS     = 5.0
+)
+
+// localePattern accepts BCP 47 language tags such as "en", "en-US" or "zh-Hant-TW"
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8}){0,3}$`)
+
+// ValidateBrowserSettings checks browser settings from a session create request
+func ValidateBrowserSettings(settings *types.BrowserSettings) error {
+	if settings == nil {
+		return nil
+	}
+
+	if v := settings.Viewport; v != nil {
+		if v.Width < minViewportWidth || v.Width > maxViewportWidth {
+			return fmt.Errorf("viewport.width must be between %d and %d", minViewportWidth, maxViewportWidth)
+		}
+		if v.Height < minViewportHeight || v.Height > maxViewportHeight {
+			return fmt.Errorf("viewport.height must be between %d and %d", minViewportHeight, maxViewportHeight)
+		}
+	}
+
+	if dsf := settings.DeviceScaleFactor; dsf != 0 && (dsf < minDeviceScaleFactor || dsf > maxDeviceScaleFactor) {
+		return fmt.Errorf("deviceScaleFactor must be between %g and %g", float64(minDeviceScaleFactor), float64(maxDeviceScaleFactor))
+	}
+
+	if len(settings.UserAgent) > maxUserAgentLength {
+		return fmt.Errorf("userAgent must be at most %d characters", maxUserAgentLength)
+	}
+	if strings.ContainsAny(settings.UserAgent, "\r\n") {
+		return fmt.Errorf("userAgent must not contain line breaks")
+	}
+
+	if settings.Locale != "" && !localePattern.MatchString(settings.Locale) {
+		return fmt.Errorf("locale must be a language tag such as en-US")
+	}
+
+	if settings.Timezone != "" {
+		if _, err := time.LoadLocation(settings.Timezone); err != nil || strings.EqualFold(settings.Timezone, "Local") {
+			return fmt.Errorf("timezone must be an IANA time zone such as America/New_York")
+		}
+	}
+
+	if g := settings.Geolocation; g != nil {
+		if g.Latitude < -90 || g.Latitude > 90 {
+			return fmt.Errorf("geolocation.latitude must be between -90 and 90")
+		}
+		if g.Longitude < -180 || g.Longitude > 180 {
+			return fmt.Errorf("geolocation.longitude must be between -180 and 180")
+		}
+		if g.Accuracy < 0 {
+			return fmt.Errorf("geolocation.accuracy must not be negative")
+		}
+	}
+
+	return nil
+}
+
+// ValidateScreencastFPS checks the screencastFps setting; 0 selects DefaultScreencastFPS
+func ValidateScreencastFPS(fps float64) error {
+	if fps < 0 || fps > MaxScreencastFPS {
+		return fmt.Errorf("screencastFps must be between 0 and %g", MaxScreencastFPS)
+	}
+	return nil
+}
+
+// IsEmptyBrowserSettings reports whether no browser setting differs from the defaults
+func IsEmptyBrowserSettings(settings *types.BrowserSettings) bool {
+	return settings == nil || *settings == (types.BrowserSettings{})
+}
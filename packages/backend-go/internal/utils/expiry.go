@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+// SessionStatusExpiresAtIndex indexes sessions by SDK status and expiresAt (keys only)
+const SessionStatusExpiresAtIndex = "status-expiresAt-index"
+
+// TaskStopReasonOrphaned is the StopTask reason for tasks without a live session
+const TaskStopReasonOrphaned = "No live session"
+
+// ErrSessionNotExpired is returned by MarkSessionTimedOut when the session already ended
+// or its expiration was moved past now
+var ErrSessionNotExpired = errors.New("session is not expired")
+
+// ListExpiredSessionIDs returns the sessions with the given SDK status whose expiresAt is
+// at or before the given time, using the status-expiresAt index instead of a table scan
+func ListExpiredSessionIDs(ctx context.Context, ddbClient *dynamodb.Client, status string, before time.Time) ([]string, error) {
+	var sessionIDs []string
+	paginator := dynamodb.NewQueryPaginator(ddbClient, &dynamodb.QueryInput{
+		TableName:              aws.String(SessionsTableName),
+		IndexName:              aws.String(SessionStatusExpiresAtIndex),
+		KeyConditionExpression: aws.String("#status = :status AND expiresAt <= :before"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":status": &dynamotypes.AttributeValueMemberS{Value: status},
+			":before": &dynamotypes.AttributeValueMemberN{Value: strconv.FormatInt(before.Unix(), 10)},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if sessionID := getStringValue(item["sessionId"]); sessionID != "" {
+				sessionIDs = append(sessionIDs, sessionID)
+			}
+		}
+	}
+	return sessionIDs, nil
+}
+
+// MarkSessionTimedOut moves a session that is still running past its expiresAt to
+// TIMED_OUT. Sessions that already ended, or were extended in the meantime, are left
+// alone and a *SessionTransitionError matching ErrSessionNotExpired is returned.
+func MarkSessionTimedOut(ctx context.Context, ddbClient *dynamodb.Client, sessionID string, now time.Time) error {
+	_, err := updateSession(ctx, ddbClient, sessionID, sessionUpdate{
+		to:        types.SessionStatusTimedOut,
+		set:       []string{"endedAt = :now"},
+		condition: "expiresAt <= :nowUnix",
+		values: map[string]dynamotypes.AttributeValue{
+			":nowUnix": &dynamotypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+		rejected: ErrSessionNotExpired,
+	})
+	return err
+}
@@ -14,27 +14,47 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/wallcrawler/backend-go/internal/types"
 )
 
+// CDPTokenScopeView marks tokens that may watch a session but not drive it.
+// Tokens without a scope grant full control.
+const CDPTokenScopeView = "view"
+
+// CDPTokenScopeInternal marks short-lived tokens minted by the API for calls to the
+// controller's internal endpoints.
+const CDPTokenScopeInternal = "internal"
+
 // CDPSigningPayload represents the data structure for CDP access tokens
 type CDPSigningPayload struct {
-	SessionID string `json:"sessionId"`
-	ProjectID string `json:"projectId"`
-	UserID    string `json:"userId,omitempty"`
-	IssuedAt  int64  `json:"iat"`
-	ExpiresAt int64  `json:"exp"`
-	Nonce     string `json:"nonce"`
-	IPAddress string `json:"ipAddress,omitempty"`
+	SessionID  string           `json:"sessionId"`
+	ProjectID  string           `json:"projectId"`
+	UserID     string           `json:"userId,omitempty"`
+	IssuedAt   int64            `json:"iat"`
+	ExpiresAt  int64            `json:"exp"`
+	Nonce      string           `json:"nonce"`
+	IPAddress  string           `json:"ipAddress,omitempty"`
+	CDPPolicy  *types.CDPPolicy `json:"cdpPolicy,omitempty"`
+	Scope      string           `json:"scope,omitempty"`
+	MaxViewers int              `json:"maxViewers,omitempty"` // Concurrent connections allowed with this token, 0 = unlimited
 }
 
 // CDPTokenClaims extends jwt.RegisteredClaims with our custom fields
 type CDPTokenClaims struct {
 	jwt.RegisteredClaims
-	SessionID string `json:"sessionId"`
-	ProjectID string `json:"projectId"`
-	UserID    string `json:"userId,omitempty"`
-	Nonce     string `json:"nonce"`
-	IPAddress string `json:"ipAddress,omitempty"`
+	SessionID  string           `json:"sessionId"`
+	ProjectID  string           `json:"projectId"`
+	UserID     string           `json:"userId,omitempty"`
+	Nonce      string           `json:"nonce"`
+	IPAddress  string           `json:"ipAddress,omitempty"`
+	CDPPolicy  *types.CDPPolicy `json:"cdpPolicy,omitempty"`
+	Scope      string           `json:"scope,omitempty"`
+	MaxViewers int              `json:"maxViewers,omitempty"`
+}
+
+// IsViewOnly reports whether the token only grants read-only access
+func (p *CDPSigningPayload) IsViewOnly() bool {
+	return p.Scope == CDPTokenScopeView
 }
 
 // SecretValue represents the structure of our JWT secret in Secrets Manager
@@ -167,11 +187,14 @@ func CreateCDPToken(payload CDPSigningPayload) (string, error) {
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			ID:        payload.Nonce,
 		},
-		SessionID: payload.SessionID,
-		ProjectID: payload.ProjectID,
-		UserID:    payload.UserID,
-		Nonce:     payload.Nonce,
-		IPAddress: payload.IPAddress,
+		SessionID:  payload.SessionID,
+		ProjectID:  payload.ProjectID,
+		UserID:     payload.UserID,
+		Nonce:      payload.Nonce,
+		IPAddress:  payload.IPAddress,
+		CDPPolicy:  payload.CDPPolicy,
+		Scope:      payload.Scope,
+		MaxViewers: payload.MaxViewers,
 	}
 
 	// Create token with claims
@@ -232,13 +255,16 @@ func ValidateCDPToken(tokenString string) (*CDPSigningPayload, error) {
 
 		// Convert back to CDPSigningPayload
 		payload := &CDPSigningPayload{
-			SessionID: claims.SessionID,
-			ProjectID: claims.ProjectID,
-			UserID:    claims.UserID,
-			IssuedAt:  claims.IssuedAt.Unix(),
-			ExpiresAt: claims.ExpiresAt.Unix(),
-			Nonce:     claims.Nonce,
-			IPAddress: claims.IPAddress,
+			SessionID:  claims.SessionID,
+			ProjectID:  claims.ProjectID,
+			UserID:     claims.UserID,
+			IssuedAt:   claims.IssuedAt.Unix(),
+			ExpiresAt:  claims.ExpiresAt.Unix(),
+			Nonce:      claims.Nonce,
+			IPAddress:  claims.IPAddress,
+			CDPPolicy:  claims.CDPPolicy,
+			Scope:      claims.Scope,
+			MaxViewers: claims.MaxViewers,
 		}
 
 		return payload, nil
@@ -47,3 +47,15 @@ func GetProjectMetadata(ctx context.Context, ddbClient *dynamodb.Client, project
 
 	return &project, nil
 }
+
+// DefaultMaxUploadBytes caps session uploads for projects without their own limit.
+// Uploads travel as multipart bodies, so they also have to fit the Lambda payload limit.
+const DefaultMaxUploadBytes int64 = 4 << 20
+
+// ProjectMaxUploadBytes returns the per-file session upload cap for a project
+func ProjectMaxUploadBytes(project *types.Project) int64 {
+	if project == nil || project.MaxUploadBytes <= 0 {
+		return DefaultMaxUploadBytes
+	}
+	return project.MaxUploadBytes
+}
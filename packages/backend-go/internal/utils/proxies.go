@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+const (
+	// ProxyTypeExternal is a customer-supplied upstream proxy
+	ProxyTypeExternal = "external"
+
+	maxSessionProxies = 10
+)
+
+// supportedProxySchemes are the upstream protocols the controller's forwarding proxy can speak
+var supportedProxySchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"socks5": true,
+}
+
+// ParseSessionProxies converts the "proxies" field of a session create request.
+// It accepts false or an array of external proxy configurations.
+func ParseSessionProxies(raw interface{}) ([]types.ProxyConfig, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if v {
+			return nil, fmt.Errorf("managed proxies are not supported; provide external proxy configurations")
+		}
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("proxies must be an array")
+	}
+
+	var proxies []types.ProxyConfig
+	if err := json.Unmarshal(data, &proxies); err != nil {
+		return nil, fmt.Errorf("proxies must be an array of proxy configurations")
+	}
+
+	if err := ValidateSessionProxies(proxies); err != nil {
+		return nil, err
+	}
+	return proxies, nil
+}
+
+// ValidateSessionProxies checks upstream proxy configurations
+func ValidateSessionProxies(proxies []types.ProxyConfig) error {
+	if len(proxies) > maxSessionProxies {
+		return fmt.Errorf("at most %d proxies may be configured", maxSessionProxies)
+	}
+
+	for i, p := range proxies {
+		if p.Type != ProxyTypeExternal {
+			return fmt.Errorf("proxies[%d].type must be %q", i, ProxyTypeExternal)
+		}
+
+		server, err := url.Parse(p.Server)
+		if err != nil || !supportedProxySchemes[server.Scheme] || server.Hostname() == "" {
+			return fmt.Errorf("proxies[%d].server must be an http://, https:// or socks5:// URL with a host", i)
+		}
+		if server.Port() == "" {
+			return fmt.Errorf("proxies[%d].server must include a port", i)
+		}
+		if server.User != nil {
+			return fmt.Errorf("proxies[%d].server must not contain credentials; use username and password", i)
+		}
+
+		if p.Password != "" && p.Username == "" {
+			return fmt.Errorf("proxies[%d].password requires a username", i)
+		}
+		if len(p.Username) > 255 || len(p.Password) > 255 {
+			return fmt.Errorf("proxies[%d] username and password must be at most 255 characters", i)
+		}
+		if strings.ContainsAny(p.Username+p.Password, "\r\n") {
+			return fmt.Errorf("proxies[%d] credentials must not contain line breaks", i)
+		}
+
+		if p.DomainPattern != "" {
+			if _, err := path.Match(p.DomainPattern, ""); err != nil || strings.ContainsAny(p.DomainPattern, "/:") {
+				return fmt.Errorf("proxies[%d].domainPattern must be a host pattern such as *.example.com", i)
+			}
+		}
+	}
+	return nil
+}
+
+// MatchProxyDomain reports whether a request host matches a proxy's domain pattern.
+// A pattern like "*.example.com" also matches the apex "example.com".
+func MatchProxyDomain(pattern, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	pattern = strings.ToLower(pattern)
+
+	if ok, _ := path.Match(pattern, host); ok {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return host == pattern[2:]
+	}
+	return false
+}
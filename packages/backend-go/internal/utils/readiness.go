@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+// Readiness polling backoff
+const (
+	readyPollInitialDelay = 250 * time.Millisecond
+	readyPollMaxDelay     = 2 * time.Second
+)
+
+// ErrSessionStartFailed is returned by WaitForSessionReady when the session reaches a
+// terminal status before it becomes ready
+var ErrSessionStartFailed = errors.New("session failed to start")
+
+// ErrSessionNotStarting is returned by MarkSessionReady when the session already left the
+// starting statuses, for example because sessions-create timed out waiting for it
+var ErrSessionNotStarting = errors.New("session is no longer starting")
+
+// ErrSessionAlreadyEnded is returned by MarkSessionFailed when the session already reached
+// a terminal status
+var ErrSessionAlreadyEnded = errors.New("session already ended")
+
+// SessionEndpoints are the connection details the controller advertises once the browser
+// is usable
+type SessionEndpoints struct {
+	TaskARN    string
+	PublicIP   string
+	ConnectURL string
+}
+
+// WaitForSessionReady polls the session record, backing off between reads, until the
+// session is ready and has a public IP. It only depends on the record, so it works from
+// any Lambda instance. The wait ends with ErrSessionStartFailed if the session fails or
+// stops first, or with the context's error when ctx is done.
+func WaitForSessionReady(ctx context.Context, ddbClient *dynamodb.Client, sessionID string) (*types.SessionState, error) {
+	delay := readyPollInitialDelay
+	for {
+		sessionState, err := GetSession(ctx, ddbClient, sessionID)
+		if err == nil {
+			switch status := sessionState.InternalStatus; {
+			case (status == types.SessionStatusReady || status == types.SessionStatusActive) && sessionState.PublicIP != "":
+				return sessionState, nil
+			case IsSessionTerminal(status):
+				return sessionState, ErrSessionStartFailed
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > readyPollMaxDelay {
+			delay = readyPollMaxDelay
+		}
+	}
+}
+
+// SetSessionTaskARN records the session's ECS task without rewriting the rest of the
+// record, which the task may already be updating
+func SetSessionTaskARN(ctx context.Context, ddbClient *dynamodb.Client, sessionID, taskARN string) error {
+	_, err := updateSession(ctx, ddbClient, sessionID, sessionUpdate{
+		set: []string{"ecsTaskArn = :taskArn"},
+		values: map[string]dynamotypes.AttributeValue{
+			":taskArn": &dynamotypes.AttributeValueMemberS{Value: taskARN},
+		},
+	})
+	return err
+}
+
+// SetSessionTaskAddress records the ECS task and the public IP it was assigned, leaving the
+// status to the controller
+func SetSessionTaskAddress(ctx context.Context, ddbClient *dynamodb.Client, sessionID, taskARN, publicIP string) error {
+	_, err := updateSession(ctx, ddbClient, sessionID, sessionUpdate{
+		set: []string{"ecsTaskArn = :taskArn", "publicIP = :publicIP"},
+		values: map[string]dynamotypes.AttributeValue{
+			":taskArn":  &dynamotypes.AttributeValueMemberS{Value: taskARN},
+			":publicIP": &dynamotypes.AttributeValueMemberS{Value: publicIP},
+		},
+	})
+	return err
+}
+
+// MarkSessionReady moves a starting session to READY together with the endpoints clients
+// connect to. The write only applies while the session is still CREATING, PROVISIONING or
+// STARTING; otherwise a *SessionTransitionError matching ErrSessionNotStarting is returned.
+func MarkSessionReady(ctx context.Context, ddbClient *dynamodb.Client, sessionID string, endpoints SessionEndpoints) error {
+	_, err := updateSession(ctx, ddbClient, sessionID, sessionUpdate{
+		to:  types.SessionStatusReady,
+		set: []string{"readyAt = :now", "ecsTaskArn = :taskArn", "publicIP = :publicIP", "connectUrl = :connectUrl"},
+		values: map[string]dynamotypes.AttributeValue{
+			":taskArn":    &dynamotypes.AttributeValueMemberS{Value: endpoints.TaskARN},
+			":publicIP":   &dynamotypes.AttributeValueMemberS{Value: endpoints.PublicIP},
+			":connectUrl": &dynamotypes.AttributeValueMemberS{Value: endpoints.ConnectURL},
+		},
+		rejected: ErrSessionNotStarting,
+	})
+	return err
+}
+
+// MarkSessionFailed moves a session to FAILED and records why. Sessions that already
+// ended keep their status, and a *SessionTransitionError matching ErrSessionAlreadyEnded
+// is returned.
+func MarkSessionFailed(ctx context.Context, ddbClient *dynamodb.Client, sessionID, reason string) error {
+	_, err := updateSession(ctx, ddbClient, sessionID, sessionUpdate{
+		to:  types.SessionStatusFailed,
+		set: []string{"failureReason = :reason", "endedAt = :now"},
+		values: map[string]dynamotypes.AttributeValue{
+			":reason": &dynamotypes.AttributeValueMemberS{Value: reason},
+		},
+		rejected: ErrSessionAlreadyEnded,
+	})
+	return err
+}
@@ -2,6 +2,9 @@ package utils
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -81,6 +84,28 @@ func GenerateDownloadURL(ctx context.Context, bucket, key string, expires time.D
 	return result.URL, nil
 }
 
+// SessionArtifactKey returns the S3 key for a per-session artifact, stored alongside
+// the project's context archives.
+func SessionArtifactKey(projectID, sessionID, name string) string {
+	return fmt.Sprintf("%s/sessions/%s/%s", projectID, sessionID, name)
+}
+
+// CDPRecordingS3Key returns the S3 key of a session's CDP traffic log
+func CDPRecordingS3Key(projectID, sessionID string) string {
+	return SessionArtifactKey(projectID, sessionID, "cdp-recording.jsonl")
+}
+
+// ScreencastS3Key returns the S3 key of a session's screencast timelapse
+func ScreencastS3Key(projectID, sessionID string) string {
+	return SessionArtifactKey(projectID, sessionID, "screencast.mjpeg")
+}
+
+// SessionArtifactsPrefix returns the S3 prefix of a session's artifacts. Artifacts are
+// the objects directly under it; downloads, uploads and recording chunks live in subfolders.
+func SessionArtifactsPrefix(projectID, sessionID string) string {
+	return SessionArtifactKey(projectID, sessionID, "")
+}
+
 // NewUploader returns an S3 uploader bound to the shared client.
 func NewUploader(ctx context.Context) (*manager.Uploader, error) {
 	client, err := GetS3Client(ctx)
@@ -89,3 +114,42 @@ func NewUploader(ctx context.Context) (*manager.Uploader, error) {
 	}
 	return manager.NewUploader(client), nil
 }
+
+// SessionDownloadsPrefix returns the S3 prefix holding a session's browser downloads
+func SessionDownloadsPrefix(projectID, sessionID string) string {
+	return SessionArtifactKey(projectID, sessionID, "downloads/")
+}
+
+// SessionDownloadKey returns the S3 key of one browser download. Downloads are keyed by
+// Chrome's download GUID so files with the same name do not collide; the original
+// filename is kept as the last path segment.
+func SessionDownloadKey(projectID, sessionID, downloadID, filename string) string {
+	return SessionDownloadsPrefix(projectID, sessionID) + downloadID + "/" + filename
+}
+
+// SessionUploadKey returns the S3 key where an uploaded file is staged until the
+// session's controller pulls it
+func SessionUploadKey(projectID, sessionID, uploadID, filename string) string {
+	return SessionArtifactKey(projectID, sessionID, "uploads/"+uploadID+"/"+filename)
+}
+
+// SessionRecordingPrefix returns the S3 prefix holding a session's DOM recording
+func SessionRecordingPrefix(projectID, sessionID string) string {
+	return SessionArtifactKey(projectID, sessionID, "recording/")
+}
+
+// SessionRecordingChunkKey returns the S3 key of one chunk of a page's DOM recording.
+// Zero-padded sequence numbers keep a page's chunks in order when listed.
+func SessionRecordingChunkKey(projectID, sessionID, pageID string, seq int) string {
+	return fmt.Sprintf("%s%s/%06d.json", SessionRecordingPrefix(projectID, sessionID), pageID, seq)
+}
+
+// SanitizeFilename keeps a client or page supplied filename usable as the last
+// segment of an S3 key and as a file name on the controller
+func SanitizeFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+// SessionLogsTableName is the table holding captured page logs, keyed by sessionId and logId
+var SessionLogsTableName = os.Getenv("SESSION_LOGS_TABLE_NAME")
+
+// ErrInvalidLogCursor is returned by QuerySessionLogs for a cursor it did not issue
+var ErrInvalidLogCursor = errors.New("invalid cursor")
+
+const (
+	// SessionLogRetention is how long captured logs are kept after they are written
+	SessionLogRetention = 30 * 24 * time.Hour
+
+	// MaxSessionLogPageSize caps the entries returned by one QuerySessionLogs call
+	MaxSessionLogPageSize = 1000
+
+	batchWriteMaxItems = 25 // DynamoDB BatchWriteItem limit
+	batchWriteRetries  = 3
+)
+
+// SessionLogID builds a log sort key from the capture time and a per-controller sequence,
+// so keys sort by time and time ranges map onto key ranges
+func SessionLogID(at time.Time, seq int64) string {
+	return fmt.Sprintf("%013d-%08d", at.UnixMilli(), seq%100000000)
+}
+
+// WriteSessionLogs stores log entries in batches, retrying items DynamoDB leaves unprocessed
+func WriteSessionLogs(ctx context.Context, ddbClient *dynamodb.Client, entries []types.SessionLogEntry) error {
+	for start := 0; start < len(entries); start += batchWriteMaxItems {
+		end := start + batchWriteMaxItems
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		requests := make([]dynamotypes.WriteRequest, 0, end-start)
+		for _, entry := range entries[start:end] {
+			item, err := attributevalue.MarshalMap(entry)
+			if err != nil {
+				return fmt.Errorf("failed to marshal log entry: %v", err)
+			}
+			requests = append(requests, dynamotypes.WriteRequest{
+				PutRequest: &dynamotypes.PutRequest{Item: item},
+			})
+		}
+
+		pending := map[string][]dynamotypes.WriteRequest{SessionLogsTableName: requests}
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt > batchWriteRetries {
+				return fmt.Errorf("%d log entries left unprocessed", len(pending[SessionLogsTableName]))
+			}
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+			}
+
+			result, err := ddbClient.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: pending,
+			})
+			if err != nil {
+				return err
+			}
+			pending = result.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// SessionLogQuery selects a page of a session's logs
+type SessionLogQuery struct {
+	SessionID string
+	ProjectID string   // When set, only entries written for this project are returned
+	Types     []string // Any of these types; empty matches all
+	Levels    []string // Any of these levels; empty matches all
+	Start     time.Time
+	End       time.Time
+	Limit     int
+	Cursor    string // NextCursor of the previous page
+}
+
+// QuerySessionLogs returns one page of a session's logs in time order, and the cursor
+// of the next page or "" when there are no more entries
+func QuerySessionLogs(ctx context.Context, ddbClient *dynamodb.Client, query SessionLogQuery) ([]types.SessionLogEntry, string, error) {
+	limit := query.Limit
+	if limit <= 0 || limit > MaxSessionLogPageSize {
+		limit = MaxSessionLogPageSize
+	}
+
+	// Time bounds are compared against the millisecond prefix of logId
+	lower := fmt.Sprintf("%013d", int64(0))
+	if !query.Start.IsZero() {
+		lower = fmt.Sprintf("%013d", query.Start.UnixMilli())
+	}
+	upper := "9999999999999~"
+	if !query.End.IsZero() {
+		upper = fmt.Sprintf("%013d~", query.End.UnixMilli())
+	}
+
+	names := map[string]string{}
+	values := map[string]dynamotypes.AttributeValue{
+		":sessionId": &dynamotypes.AttributeValueMemberS{Value: query.SessionID},
+		":lower":     &dynamotypes.AttributeValueMemberS{Value: lower},
+		":upper":     &dynamotypes.AttributeValueMemberS{Value: upper},
+	}
+	var filters []string
+	if query.ProjectID != "" {
+		values[":projectId"] = &dynamotypes.AttributeValueMemberS{Value: query.ProjectID}
+		filters = append(filters, "projectId = :projectId")
+	}
+	if len(query.Types) > 0 {
+		names["#type"] = "type"
+		filters = append(filters, inFilter("#type", ":type", query.Types, values))
+	}
+	if len(query.Levels) > 0 {
+		names["#level"] = "level"
+		filters = append(filters, inFilter("#level", ":level", query.Levels, values))
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(SessionLogsTableName),
+		KeyConditionExpression:    aws.String("sessionId = :sessionId AND logId BETWEEN :lower AND :upper"),
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(true),
+		Limit:                     aws.Int32(int32(limit)),
+	}
+	if len(names) > 0 {
+		input.ExpressionAttributeNames = names
+	}
+	if len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+
+	if query.Cursor != "" {
+		logID, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+		if err != nil || len(logID) == 0 {
+			return nil, "", ErrInvalidLogCursor
+		}
+		input.ExclusiveStartKey = map[string]dynamotypes.AttributeValue{
+			"sessionId": &dynamotypes.AttributeValueMemberS{Value: query.SessionID},
+			"logId":     &dynamotypes.AttributeValueMemberS{Value: string(logID)},
+		}
+	}
+
+	// Filters apply after DynamoDB's limit, so keep reading until the page is full
+	entries := []types.SessionLogEntry{}
+	for {
+		result, err := ddbClient.Query(ctx, input)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, item := range result.Items {
+			var entry types.SessionLogEntry
+			if err := attributevalue.UnmarshalMap(item, &entry); err != nil {
+				continue
+			}
+			entries = append(entries, entry)
+			if len(entries) == limit {
+				return entries, base64.RawURLEncoding.EncodeToString([]byte(entry.LogID)), nil
+			}
+		}
+
+		if result.LastEvaluatedKey == nil {
+			return entries, "", nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// inFilter builds "name IN (:p0, :p1, ...)" and adds the placeholder values
+func inFilter(name, placeholder string, options []string, values map[string]dynamotypes.AttributeValue) string {
+	keys := make([]string, 0, len(options))
+	for i, option := range options {
+		key := fmt.Sprintf("%s%d", placeholder, i)
+		values[key] = &dynamotypes.AttributeValueMemberS{Value: option}
+		keys = append(keys, key)
+	}
+	return fmt.Sprintf("%s IN (%s)", name, strings.Join(keys, ", "))
+}
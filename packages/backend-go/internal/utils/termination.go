@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+// Reasons passed to StopTask, reported back in the task's stoppedReason
+const (
+	TaskStopReasonEnded    = "Session ended"
+	TaskStopReasonTimedOut = "Session timed out"
+)
+
+// ErrTaskStopAlreadyRecorded is returned by RecordSessionTaskStopped when an earlier event
+// for the same task (DEPROVISIONING before STOPPED) already finalized the session
+var ErrTaskStopAlreadyRecorded = errors.New("task stop already recorded")
+
+// RecordSessionTaskStopped finalizes a session whose ECS task stopped. A session that has
+// not ended yet moves to status with endedAt set to stoppedAt; one that already ended
+// (released, timed out, or failed by its controller) keeps its status. It returns the
+// session's final internal status.
+func RecordSessionTaskStopped(ctx context.Context, ddbClient *dynamodb.Client, sessionID, status, reason string, stoppedAt time.Time) (string, error) {
+	endedAt := &dynamotypes.AttributeValueMemberS{Value: stoppedAt.Format(time.RFC3339)}
+
+	update := sessionUpdate{
+		to:        status,
+		set:       []string{"endedAt = :endedAt", "taskStoppedAt = :endedAt"},
+		values:    map[string]dynamotypes.AttributeValue{":endedAt": endedAt},
+		condition: "attribute_not_exists(taskStoppedAt)",
+	}
+	if status == types.SessionStatusFailed && reason != "" {
+		update.set = append(update.set, "failureReason = :reason")
+		update.values[":reason"] = &dynamotypes.AttributeValueMemberS{Value: reason}
+	}
+
+	_, err := updateSession(ctx, ddbClient, sessionID, update)
+	if err == nil {
+		return status, nil
+	}
+	if !errors.Is(err, ErrInvalidSessionTransition) {
+		return "", err
+	}
+
+	// The session already ended; only record that its task is gone
+	attributes, err := updateSession(ctx, ddbClient, sessionID, sessionUpdate{
+		set:       []string{"endedAt = if_not_exists(endedAt, :endedAt)", "taskStoppedAt = :endedAt"},
+		values:    map[string]dynamotypes.AttributeValue{":endedAt": endedAt},
+		condition: "attribute_not_exists(taskStoppedAt)",
+		rejected:  ErrTaskStopAlreadyRecorded,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return getStringValue(attributes["internalStatus"]), nil
+}
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+// sessionTransitions is the session lifecycle: the internal statuses each status may move
+// to. Forward skips are allowed because not every writer sees every step (the controller
+// marks a session READY whether or not sessions-create recorded PROVISIONING yet).
+// STOPPED, FAILED and TIMED_OUT have no transitions and are final.
+var sessionTransitions = map[string][]string{
+	types.SessionStatusCreating: {
+		types.SessionStatusProvisioning, types.SessionStatusStarting, types.SessionStatusReady,
+		types.SessionStatusTerminating, types.SessionStatusStopped, types.SessionStatusFailed, types.SessionStatusTimedOut,
+	},
+	types.SessionStatusProvisioning: {
+		types.SessionStatusStarting, types.SessionStatusReady,
+		types.SessionStatusTerminating, types.SessionStatusStopped, types.SessionStatusFailed, types.SessionStatusTimedOut,
+	},
+	types.SessionStatusStarting: {
+		types.SessionStatusReady,
+		types.SessionStatusTerminating, types.SessionStatusStopped, types.SessionStatusFailed, types.SessionStatusTimedOut,
+	},
+	types.SessionStatusReady: {
+		types.SessionStatusActive,
+		types.SessionStatusTerminating, types.SessionStatusStopped, types.SessionStatusFailed, types.SessionStatusTimedOut,
+	},
+	types.SessionStatusActive: {
+		types.SessionStatusTerminating, types.SessionStatusStopped, types.SessionStatusFailed, types.SessionStatusTimedOut,
+	},
+	types.SessionStatusTerminating: {
+		types.SessionStatusStopped, types.SessionStatusFailed, types.SessionStatusTimedOut,
+	},
+}
+
+// sessionStatusOrder lists the non-final statuses in lifecycle order, so condition
+// expressions built from the table are stable
+var sessionStatusOrder = []string{
+	types.SessionStatusCreating,
+	types.SessionStatusProvisioning,
+	types.SessionStatusStarting,
+	types.SessionStatusReady,
+	types.SessionStatusActive,
+	types.SessionStatusTerminating,
+}
+
+var (
+	// ErrInvalidSessionTransition matches writes rejected because the session's current
+	// status cannot move to the requested one (for example, a stopped session being marked
+	// ready again)
+	ErrInvalidSessionTransition = errors.New("invalid session status transition")
+
+	// ErrStaleSessionVersion matches writes rejected because the record changed after the
+	// caller read it
+	ErrStaleSessionVersion = errors.New("session was modified concurrently")
+)
+
+// SessionTransitionError is returned when a conditional session write is rejected. From and
+// Version describe the record as it was when the write failed, so handlers can decide
+// whether the outcome they wanted already happened.
+type SessionTransitionError struct {
+	SessionID string
+	From      string
+	To        string
+	Version   int64
+	// Err is ErrStaleSessionVersion, ErrInvalidSessionTransition, or a more specific
+	// sentinel from the helper that made the write (such as ErrSessionNotStarting)
+	Err error
+}
+
+func (e *SessionTransitionError) Error() string {
+	if e.To == "" {
+		return fmt.Sprintf("session %s (%s, version %d): %v", e.SessionID, e.From, e.Version, e.Err)
+	}
+	return fmt.Sprintf("session %s %s -> %s (version %d): %v", e.SessionID, e.From, e.To, e.Version, e.Err)
+}
+
+func (e *SessionTransitionError) Unwrap() error {
+	return e.Err
+}
+
+// Is lets every rejection other than a stale version match ErrInvalidSessionTransition,
+// whatever specific sentinel it carries
+func (e *SessionTransitionError) Is(target error) bool {
+	return target == ErrInvalidSessionTransition && !errors.Is(e.Err, ErrStaleSessionVersion)
+}
+
+// CanTransitionSession reports whether a session may move from one internal status to another
+func CanTransitionSession(from, to string) bool {
+	for _, next := range sessionTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionStatusesBefore returns the statuses that may move to the given status
+func sessionStatusesBefore(to string) []string {
+	var from []string
+	for _, status := range sessionStatusOrder {
+		if CanTransitionSession(status, to) {
+			from = append(from, status)
+		}
+	}
+	return from
+}
+
+// sessionStatusCondition builds an "internalStatus IN (...)" condition for the given
+// statuses, adding their placeholders to values
+func sessionStatusCondition(statuses []string, values map[string]dynamotypes.AttributeValue) string {
+	placeholders := make([]string, 0, len(statuses))
+	for i, status := range statuses {
+		placeholder := ":fromStatus" + strconv.Itoa(i)
+		values[placeholder] = &dynamotypes.AttributeValueMemberS{Value: status}
+		placeholders = append(placeholders, placeholder)
+	}
+	return "internalStatus IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+// sessionUpdate is a conditional write to a session record. Every update bumps the record's
+// version, so callers that read the session first can detect concurrent changes.
+type sessionUpdate struct {
+	// to is the internal status to move to; empty leaves the status alone
+	to string
+	// set holds extra "attr = :value" SET clauses, with their values in values
+	set    []string
+	values map[string]dynamotypes.AttributeValue
+	// condition is ANDed with the existence and transition checks
+	condition string
+	// rejected is the sentinel reported when the condition fails; it defaults to
+	// ErrInvalidSessionTransition
+	rejected error
+}
+
+// updateSession applies a sessionUpdate and returns the record as written. Rejected writes
+// return a *SessionTransitionError; a missing record returns ErrSessionNotFound.
+func updateSession(ctx context.Context, ddbClient *dynamodb.Client, sessionID string, update sessionUpdate) (map[string]dynamotypes.AttributeValue, error) {
+	now := time.Now().Format(time.RFC3339)
+
+	values := map[string]dynamotypes.AttributeValue{
+		":now": &dynamotypes.AttributeValueMemberS{Value: now},
+		":one": &dynamotypes.AttributeValueMemberN{Value: "1"},
+	}
+	for key, value := range update.values {
+		values[key] = value
+	}
+	names := map[string]string{}
+
+	set := []string{"updatedAt = :now"}
+	conditions := []string{"attribute_exists(sessionId)"}
+
+	if update.to != "" {
+		names["#status"] = "status"
+		values[":sdkStatus"] = &dynamotypes.AttributeValueMemberS{Value: MapStatusToSDK(update.to)}
+		values[":toStatus"] = &dynamotypes.AttributeValueMemberS{Value: update.to}
+		set = append(set, "#status = :sdkStatus", "internalStatus = :toStatus")
+
+		from := sessionStatusesBefore(update.to)
+		if len(from) == 0 {
+			return nil, &SessionTransitionError{SessionID: sessionID, To: update.to, Err: ErrInvalidSessionTransition}
+		}
+		conditions = append(conditions, sessionStatusCondition(from, values))
+	}
+	set = append(set, update.set...)
+
+	if update.condition != "" {
+		conditions = append(conditions, update.condition)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(SessionsTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"sessionId": &dynamotypes.AttributeValueMemberS{Value: sessionID},
+		},
+		UpdateExpression:                    aws.String("SET " + strings.Join(set, ", ") + " ADD version :one"),
+		ConditionExpression:                 aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeValues:           values,
+		ReturnValues:                        dynamotypes.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: dynamotypes.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+	if len(names) > 0 {
+		input.ExpressionAttributeNames = names
+	}
+
+	result, err := ddbClient.UpdateItem(ctx, input)
+	if err != nil {
+		return nil, sessionWriteError(err, sessionID, update.to, nil, update.rejected)
+	}
+	return result.Attributes, nil
+}
+
+// versionCondition matches records at the given version. Version 0 is a record that was
+// never written through a versioned write.
+func versionCondition(version int64, values map[string]dynamotypes.AttributeValue) string {
+	if version == 0 {
+		return "attribute_not_exists(version)"
+	}
+	values[":expectedVersion"] = &dynamotypes.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	return "version = :expectedVersion"
+}
+
+// sessionWriteError turns a failed conditional check into a *SessionTransitionError built
+// from the record's current status and version
+func sessionWriteError(err error, sessionID, to string, expectedVersion *int64, rejected error) error {
+	var conditionFailed *dynamotypes.ConditionalCheckFailedException
+	if !errors.As(err, &conditionFailed) {
+		return err
+	}
+	if conditionFailed.Item == nil {
+		return ErrSessionNotFound
+	}
+
+	transitionErr := &SessionTransitionError{
+		SessionID: sessionID,
+		From:      getStringValue(conditionFailed.Item["internalStatus"]),
+		To:        to,
+		Version:   getNumberValue(conditionFailed.Item["version"]),
+		Err:       rejected,
+	}
+	if expectedVersion != nil && transitionErr.Version != *expectedVersion {
+		transitionErr.Err = ErrStaleSessionVersion
+	}
+	if transitionErr.Err == nil {
+		transitionErr.Err = ErrInvalidSessionTransition
+	}
+	return transitionErr
+}
+
+// UpdateSessionStatus moves a session to the given internal status if the transition table
+// allows it from the status currently stored, setting the lifecycle timestamp that goes
+// with the new status. Writing the status the session already has is a no-op, so retried
+// writes succeed. Illegal transitions return a *SessionTransitionError matching
+// ErrInvalidSessionTransition.
+func UpdateSessionStatus(ctx context.Context, ddbClient *dynamodb.Client, sessionID, status string) error {
+	update := sessionUpdate{to: status}
+	switch status {
+	case types.SessionStatusProvisioning:
+		update.set = []string{"provisioningStartedAt = :now"}
+	case types.SessionStatusReady:
+		update.set = []string{"readyAt = :now"}
+	case types.SessionStatusActive:
+		update.set = []string{"lastActiveAt = :now"}
+	case types.SessionStatusTerminating, types.SessionStatusStopped, types.SessionStatusFailed, types.SessionStatusTimedOut:
+		update.set = []string{"endedAt = if_not_exists(endedAt, :now)"}
+	}
+
+	_, err := updateSession(ctx, ddbClient, sessionID, update)
+
+	// The session is already where the caller wants it; its timestamps are left untouched
+	var transitionErr *SessionTransitionError
+	if errors.As(err, &transitionErr) && transitionErr.From == status {
+		return nil
+	}
+	return err
+}
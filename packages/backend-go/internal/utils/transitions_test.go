@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/wallcrawler/backend-go/internal/types"
+)
+
+func TestCanTransitionSession(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{types.SessionStatusCreating, types.SessionStatusProvisioning, true},
+		{types.SessionStatusCreating, types.SessionStatusReady, true},
+		{types.SessionStatusProvisioning, types.SessionStatusStarting, true},
+		{types.SessionStatusStarting, types.SessionStatusReady, true},
+		{types.SessionStatusReady, types.SessionStatusActive, true},
+		{types.SessionStatusActive, types.SessionStatusTerminating, true},
+		{types.SessionStatusActive, types.SessionStatusTimedOut, true},
+		{types.SessionStatusTerminating, types.SessionStatusStopped, true},
+		{types.SessionStatusCreating, types.SessionStatusFailed, true},
+
+		// Backwards moves
+		{types.SessionStatusProvisioning, types.SessionStatusCreating, false},
+		{types.SessionStatusReady, types.SessionStatusStarting, false},
+		{types.SessionStatusActive, types.SessionStatusReady, false},
+		{types.SessionStatusTerminating, types.SessionStatusActive, false},
+
+		// Same status
+		{types.SessionStatusReady, types.SessionStatusReady, false},
+		{types.SessionStatusStopped, types.SessionStatusStopped, false},
+
+		// Skipping READY on the way to ACTIVE
+		{types.SessionStatusStarting, types.SessionStatusActive, false},
+
+		// Final statuses
+		{types.SessionStatusStopped, types.SessionStatusReady, false},
+		{types.SessionStatusFailed, types.SessionStatusStopped, false},
+		{types.SessionStatusTimedOut, types.SessionStatusFailed, false},
+
+		// Unknown statuses
+		{"", types.SessionStatusReady, false},
+		{types.SessionStatusReady, "UNKNOWN", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := CanTransitionSession(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransitionSession(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionStatusesBefore(t *testing.T) {
+	running := []string{
+		types.SessionStatusCreating,
+		types.SessionStatusProvisioning,
+		types.SessionStatusStarting,
+		types.SessionStatusReady,
+		types.SessionStatusActive,
+	}
+	nonFinal := append(append([]string{}, running...), types.SessionStatusTerminating)
+
+	tests := []struct {
+		to   string
+		want []string
+	}{
+		{types.SessionStatusCreating, nil},
+		{types.SessionStatusProvisioning, []string{types.SessionStatusCreating}},
+		{types.SessionStatusStarting, []string{types.SessionStatusCreating, types.SessionStatusProvisioning}},
+		{types.SessionStatusReady, []string{types.SessionStatusCreating, types.SessionStatusProvisioning, types.SessionStatusStarting}},
+		{types.SessionStatusActive, []string{types.SessionStatusReady}},
+		{types.SessionStatusTerminating, running},
+		{types.SessionStatusStopped, nonFinal},
+		{types.SessionStatusFailed, nonFinal},
+		{types.SessionStatusTimedOut, nonFinal},
+		{"UNKNOWN", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			if got := sessionStatusesBefore(tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sessionStatusesBefore(%q) = %v, want %v", tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+// conditionFailed stubs the exception DynamoDB returns with ReturnValuesOnConditionCheckFailure
+func conditionFailed(status, version string) *dynamotypes.ConditionalCheckFailedException {
+	item := map[string]dynamotypes.AttributeValue{
+		"sessionId":      &dynamotypes.AttributeValueMemberS{Value: "sess_1"},
+		"internalStatus": &dynamotypes.AttributeValueMemberS{Value: status},
+	}
+	if version != "" {
+		item["version"] = &dynamotypes.AttributeValueMemberN{Value: version}
+	}
+	return &dynamotypes.ConditionalCheckFailedException{Message: aws.String("The conditional request failed"), Item: item}
+}
+
+func TestSessionWriteError(t *testing.T) {
+	version := func(v int64) *int64 { return &v }
+	throttled := errors.New("throttled")
+
+	tests := []struct {
+		name            string
+		err             error
+		to              string
+		expectedVersion *int64
+		rejected        error
+
+		wantErr     error // Matched with errors.Is
+		wantNotErr  error // Must not match with errors.Is
+		wantFrom    string
+		wantVersion int64
+	}{
+		{
+			name:    "other errors pass through",
+			err:     throttled,
+			to:      types.SessionStatusReady,
+			wantErr: throttled,
+		},
+		{
+			name:    "missing record",
+			err:     &dynamotypes.ConditionalCheckFailedException{},
+			to:      types.SessionStatusReady,
+			wantErr: ErrSessionNotFound,
+		},
+		{
+			name:        "illegal transition",
+			err:         conditionFailed(types.SessionStatusStopped, "4"),
+			to:          types.SessionStatusReady,
+			wantErr:     ErrInvalidSessionTransition,
+			wantNotErr:  ErrStaleSessionVersion,
+			wantFrom:    types.SessionStatusStopped,
+			wantVersion: 4,
+		},
+		{
+			name:        "illegal transition on a record without a version",
+			err:         conditionFailed(types.SessionStatusFailed, ""),
+			to:          types.SessionStatusStopped,
+			wantErr:     ErrInvalidSessionTransition,
+			wantFrom:    types.SessionStatusFailed,
+			wantVersion: 0,
+		},
+		{
+			name:        "helper sentinel",
+			err:         conditionFailed(types.SessionStatusStopped, "2"),
+			to:          types.SessionStatusReady,
+			rejected:    ErrSessionNotStarting,
+			wantErr:     ErrSessionNotStarting,
+			wantNotErr:  ErrStaleSessionVersion,
+			wantFrom:    types.SessionStatusStopped,
+			wantVersion: 2,
+		},
+		{
+			name:            "stale version",
+			err:             conditionFailed(types.SessionStatusReady, "7"),
+			to:              types.SessionStatusReady,
+			expectedVersion: version(6),
+			rejected:        ErrSessionNotStarting,
+			wantErr:         ErrStaleSessionVersion,
+			wantNotErr:      ErrInvalidSessionTransition,
+			wantFrom:        types.SessionStatusReady,
+			wantVersion:     7,
+		},
+		{
+			name:            "expected version but illegal transition",
+			err:             conditionFailed(types.SessionStatusTimedOut, "3"),
+			to:              types.SessionStatusActive,
+			expectedVersion: version(3),
+			wantErr:         ErrInvalidSessionTransition,
+			wantNotErr:      ErrStaleSessionVersion,
+			wantFrom:        types.SessionStatusTimedOut,
+			wantVersion:     3,
+		},
+		{
+			name:        "wrapped exception",
+			err:         fmt.Errorf("operation error DynamoDB: UpdateItem: %w", conditionFailed(types.SessionStatusActive, "5")),
+			to:          types.SessionStatusProvisioning,
+			rejected:    ErrTaskStopAlreadyRecorded,
+			wantErr:     ErrTaskStopAlreadyRecorded,
+			wantFrom:    types.SessionStatusActive,
+			wantVersion: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sessionWriteError(tt.err, "sess_1", tt.to, tt.expectedVersion, tt.rejected)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("sessionWriteError() = %v, want it to match %v", err, tt.wantErr)
+			}
+			if tt.wantNotErr != nil && errors.Is(err, tt.wantNotErr) {
+				t.Errorf("sessionWriteError() = %v, must not match %v", err, tt.wantNotErr)
+			}
+
+			var transitionErr *SessionTransitionError
+			if !errors.As(err, &transitionErr) {
+				if tt.wantFrom != "" {
+					t.Fatalf("sessionWriteError() = %v, want a *SessionTransitionError", err)
+				}
+				return
+			}
+			if transitionErr.SessionID != "sess_1" || transitionErr.To != tt.to {
+				t.Errorf("got session %q to %q, want %q to %q", transitionErr.SessionID, transitionErr.To, "sess_1", tt.to)
+			}
+			if transitionErr.From != tt.wantFrom {
+				t.Errorf("From = %q, want %q", transitionErr.From, tt.wantFrom)
+			}
+			if transitionErr.Version != tt.wantVersion {
+				t.Errorf("Version = %d, want %d", transitionErr.Version, tt.wantVersion)
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package utils
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -151,7 +153,11 @@ func ErrorResponse(message string) types.ErrorResponse {
 	}
 }
 
-// StoreSession stores session state in DynamoDB with TTL
+// StoreSession writes the full session state in DynamoDB with TTL. The write is versioned:
+// a new session (Version 0) is only created if no record exists, and an existing one is
+// only replaced if it is still at sessionState.Version and its stored status may move to
+// sessionState.InternalStatus. Rejected writes return a *SessionTransitionError; on success
+// sessionState.Version is advanced to the stored version.
 func StoreSession(ctx context.Context, ddbClient *dynamodb.Client, sessionState *types.SessionState) error {
 	// Ensure the TTL aligns with the computed session expiration
 	if sessionState.ExpiresAtUnix == 0 {
@@ -171,6 +177,7 @@ func StoreSession(ctx context.Context, ddbClient *dynamodb.Client, sessionState
 		"proxyBytes":     &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(sessionState.ProxyBytes)},
 		"publicIP":       &dynamotypes.AttributeValueMemberS{Value: sessionState.PublicIP},
 		"ecsTaskArn":     &dynamotypes.AttributeValueMemberS{Value: sessionState.ECSTaskARN},
+		"version":        &dynamotypes.AttributeValueMemberN{Value: strconv.FormatInt(sessionState.Version+1, 10)},
 	}
 
 	// Add timestamp fields (store as strings for SDK compatibility)
@@ -199,12 +206,45 @@ func StoreSession(ctx context.Context, ddbClient *dynamodb.Client, sessionState
 	if sessionState.EndedAt != nil {
 		item["endedAt"] = &dynamotypes.AttributeValueMemberS{Value: *sessionState.EndedAt}
 	}
+	if sessionState.ReadyAt != nil {
+		item["readyAt"] = &dynamotypes.AttributeValueMemberS{Value: *sessionState.ReadyAt}
+	}
+	if sessionState.FailureReason != nil {
+		item["failureReason"] = &dynamotypes.AttributeValueMemberS{Value: *sessionState.FailureReason}
+	}
+	if sessionState.TaskStoppedAt != nil {
+		item["taskStoppedAt"] = &dynamotypes.AttributeValueMemberS{Value: *sessionState.TaskStoppedAt}
+	}
+	if sessionState.ProvisioningStartedAt != nil {
+		item["provisioningStartedAt"] = &dynamotypes.AttributeValueMemberS{Value: *sessionState.ProvisioningStartedAt}
+	}
+	if sessionState.LastActiveAt != nil {
+		item["lastActiveAt"] = &dynamotypes.AttributeValueMemberS{Value: *sessionState.LastActiveAt}
+	}
+	if sessionState.LastEventTimestamp != nil {
+		item["lastEventTimestamp"] = &dynamotypes.AttributeValueMemberS{Value: *sessionState.LastEventTimestamp}
+	}
+	if sessionState.RetryCount > 0 {
+		item["retryCount"] = &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(sessionState.RetryCount)}
+	}
 	if sessionState.MemoryUsage != nil {
 		item["memoryUsage"] = &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(*sessionState.MemoryUsage)}
 	}
 	if sessionState.ContextStorageKey != nil && *sessionState.ContextStorageKey != "" {
 		item["contextStorageKey"] = &dynamotypes.AttributeValueMemberS{Value: *sessionState.ContextStorageKey}
 	}
+	if sessionState.RecordCDP {
+		item["recordCdp"] = &dynamotypes.AttributeValueMemberBOOL{Value: true}
+	}
+	if sessionState.RecordSession {
+		item["recordSession"] = &dynamotypes.AttributeValueMemberBOOL{Value: true}
+	}
+	if sessionState.RecordScreencast {
+		item["recordScreencast"] = &dynamotypes.AttributeValueMemberBOOL{Value: true}
+		if sessionState.ScreencastFPS > 0 {
+			item["screencastFps"] = &dynamotypes.AttributeValueMemberN{Value: strconv.FormatFloat(sessionState.ScreencastFPS, 'f', -1, 64)}
+		}
+	}
 
 	// Add optional fields
 	if len(sessionState.UserMetadata) > 0 {
@@ -214,6 +254,20 @@ func StoreSession(ctx context.Context, ddbClient *dynamodb.Client, sessionState
 		}
 	}
 
+	if sessionState.BillingInfo != nil {
+		billingAV, err := attributevalue.Marshal(sessionState.BillingInfo)
+		if err == nil {
+			item["billingInfo"] = billingAV
+		}
+	}
+
+	if sessionState.ResourceLimits != nil {
+		limitsAV, err := attributevalue.Marshal(sessionState.ResourceLimits)
+		if err == nil {
+			item["resourceLimits"] = limitsAV
+		}
+	}
+
 	if sessionState.ModelConfig != nil {
 		configAV, err := attributevalue.Marshal(sessionState.ModelConfig)
 		if err == nil {
@@ -221,21 +275,43 @@ func StoreSession(ctx context.Context, ddbClient *dynamodb.Client, sessionState
 		}
 	}
 
+	if !IsEmptyBrowserSettings(sessionState.BrowserSettings) {
+		settingsAV, err := attributevalue.Marshal(sessionState.BrowserSettings)
+		if err == nil {
+			item["browserSettings"] = settingsAV
+		}
+	}
+
+	// Keeping the status is always allowed; otherwise the transition table decides
+	values := map[string]dynamotypes.AttributeValue{}
+	allowed := append([]string{sessionState.InternalStatus}, sessionStatusesBefore(sessionState.InternalStatus)...)
+	condition := versionCondition(sessionState.Version, values) + " AND " + sessionStatusCondition(allowed, values)
+	if sessionState.Version == 0 {
+		condition = "attribute_not_exists(sessionId) OR (" + condition + ")"
+	}
+
 	// Store in DynamoDB
 	_, err := ddbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(SessionsTableName),
-		Item:      item,
+		TableName:                           aws.String(SessionsTableName),
+		Item:                                item,
+		ConditionExpression:                 aws.String(condition),
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: dynamotypes.ReturnValuesOnConditionCheckFailureAllOld,
 	})
 
 	if err != nil {
 		log.Printf("Error storing session %s in DynamoDB: %v", sessionState.ID, err)
-		return err
+		return sessionWriteError(err, sessionState.ID, sessionState.InternalStatus, &sessionState.Version, nil)
 	}
+	sessionState.Version++
 
 	log.Printf("Stored session %s in DynamoDB with TTL %d", sessionState.ID, sessionState.ExpiresAtUnix)
 	return nil
 }
 
+// ErrSessionNotFound is returned by GetSession when the session has no record
+var ErrSessionNotFound = errors.New("session not found")
+
 // GetSession retrieves session state from DynamoDB
 func GetSession(ctx context.Context, ddbClient *dynamodb.Client, sessionID string) (*types.SessionState, error) {
 	result, err := ddbClient.GetItem(ctx, &dynamodb.GetItemInput{
@@ -250,7 +326,7 @@ func GetSession(ctx context.Context, ddbClient *dynamodb.Client, sessionID strin
 	}
 
 	if result.Item == nil {
-		return nil, fmt.Errorf("session not found")
+		return nil, ErrSessionNotFound
 	}
 
 	// Convert DynamoDB item to SessionState
@@ -305,6 +381,16 @@ func GetSession(ctx context.Context, ddbClient *dynamodb.Client, sessionID strin
 		if endedAt := getStringValue(result.Item["endedAt"]); endedAt != "" {
 			sessionState.EndedAt = &endedAt
 		}
+		if readyAt := getStringValue(result.Item["readyAt"]); readyAt != "" {
+			sessionState.ReadyAt = &readyAt
+		}
+		if failureReason := getStringValue(result.Item["failureReason"]); failureReason != "" {
+			sessionState.FailureReason = &failureReason
+		}
+		if taskStoppedAt := getStringValue(result.Item["taskStoppedAt"]); taskStoppedAt != "" {
+			sessionState.TaskStoppedAt = &taskStoppedAt
+		}
+		sessionState.Version = getNumberValue(result.Item["version"])
 		if avgCPU := getNumberValue(result.Item["avgCpuUsage"]); avgCPU != 0 {
 			cpu := int(avgCPU)
 			sessionState.AvgCPUUsage = &cpu
@@ -316,6 +402,10 @@ func GetSession(ctx context.Context, ddbClient *dynamodb.Client, sessionID strin
 		if storageKey := getStringValue(result.Item["contextStorageKey"]); storageKey != "" {
 			sessionState.ContextStorageKey = &storageKey
 		}
+		sessionState.RecordCDP = getBoolValue(result.Item["recordCdp"])
+		sessionState.RecordSession = getBoolValue(result.Item["recordSession"])
+		sessionState.RecordScreencast = getBoolValue(result.Item["recordScreencast"])
+		sessionState.ScreencastFPS = getFloatValue(result.Item["screencastFps"])
 
 		// Parse optional fields
 		if metadata, ok := result.Item["userMetadata"]; ok {
@@ -324,6 +414,12 @@ func GetSession(ctx context.Context, ddbClient *dynamodb.Client, sessionID strin
 		if config, ok := result.Item["modelConfig"]; ok {
 			attributevalue.Unmarshal(config, &sessionState.ModelConfig)
 		}
+		if billing, ok := result.Item["billingInfo"]; ok {
+			attributevalue.Unmarshal(billing, &sessionState.BillingInfo)
+		}
+		if limits, ok := result.Item["resourceLimits"]; ok {
+			attributevalue.Unmarshal(limits, &sessionState.ResourceLimits)
+		}
 		if internalStatus := getStringValue(result.Item["internalStatus"]); internalStatus != "" {
 			sessionState.InternalStatus = internalStatus
 		}
@@ -349,6 +445,14 @@ func getNumberValue(attr dynamotypes.AttributeValue) int64 {
 	return 0
 }
 
+func getFloatValue(attr dynamotypes.AttributeValue) float64 {
+	if v, ok := attr.(*dynamotypes.AttributeValueMemberN); ok {
+		f, _ := strconv.ParseFloat(v.Value, 64)
+		return f
+	}
+	return 0
+}
+
 func getBoolValue(attr dynamotypes.AttributeValue) bool {
 	if v, ok := attr.(*dynamotypes.AttributeValueMemberBOOL); ok {
 		return v.Value
@@ -356,54 +460,6 @@ func getBoolValue(attr dynamotypes.AttributeValue) bool {
 	return false
 }
 
-// UpdateSessionStatus updates session status in Redis with proper lifecycle tracking
-func UpdateSessionStatus(ctx context.Context, ddbClient *dynamodb.Client, sessionID, status string) error {
-	sessionState, err := GetSession(ctx, ddbClient, sessionID)
-	if err != nil {
-		return err
-	}
-
-	// Update status with proper lifecycle timing
-	previousStatus := sessionState.Status
-	sessionState.Status = MapStatusToSDK(status) // Map internal status to SDK status
-	sessionState.InternalStatus = status
-	now := time.Now()
-	nowStr := now.Format(time.RFC3339)
-	sessionState.UpdatedAt = nowStr
-
-	// Track specific lifecycle timestamps
-	switch status {
-	case types.SessionStatusProvisioning:
-		sessionState.ProvisioningStartedAt = &nowStr
-	case types.SessionStatusReady:
-		sessionState.ReadyAt = &nowStr
-	case types.SessionStatusActive:
-		sessionState.LastActiveAt = &nowStr
-	case types.SessionStatusTerminating, types.SessionStatusStopped, types.SessionStatusFailed:
-		sessionState.EndedAt = &nowStr // SDK field
-	}
-
-	// Add event to history
-	sessionEvent := types.SessionEvent{
-		EventType: "StatusChanged",
-		Timestamp: nowStr,
-		Source:    "wallcrawler.utils",
-		Detail: map[string]interface{}{
-			"previousStatus": previousStatus,
-			"newStatus":      status,
-			"sessionId":      sessionID,
-		},
-	}
-
-	if sessionState.EventHistory == nil {
-		sessionState.EventHistory = []types.SessionEvent{}
-	}
-	sessionState.EventHistory = append(sessionState.EventHistory, sessionEvent)
-	sessionState.LastEventTimestamp = &nowStr
-
-	return StoreSession(ctx, ddbClient, sessionState)
-}
-
 // DeleteSession removes session from DynamoDB
 func DeleteSession(ctx context.Context, ddbClient *dynamodb.Client, sessionID string) error {
 	_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
@@ -431,16 +487,79 @@ func CreateECSTask(ctx context.Context, sessionID string, sessionState *types.Se
 		{Name: aws.String("PROJECT_ID"), Value: aws.String(sessionState.ProjectID)},
 	}
 
+	// Session artifacts (context archives, recordings) share the contexts bucket
+	if ContextsBucketName != "" {
+		env = append(env, ecstypes.KeyValuePair{Name: aws.String("CONTEXTS_BUCKET_NAME"), Value: aws.String(ContextsBucketName)})
+	}
+
 	if sessionState.ContextID != nil && *sessionState.ContextID != "" &&
 		sessionState.ContextStorageKey != nil && *sessionState.ContextStorageKey != "" && ContextsBucketName != "" {
 		env = append(env,
 			ecstypes.KeyValuePair{Name: aws.String("CONTEXT_ID"), Value: aws.String(*sessionState.ContextID)},
 			ecstypes.KeyValuePair{Name: aws.String("CONTEXT_S3_KEY"), Value: aws.String(*sessionState.ContextStorageKey)},
-			ecstypes.KeyValuePair{Name: aws.String("CONTEXTS_BUCKET_NAME"), Value: aws.String(ContextsBucketName)},
 			ecstypes.KeyValuePair{Name: aws.String("CONTEXT_PERSIST"), Value: aws.String(strconv.FormatBool(sessionState.ContextPersist))},
 		)
 	}
 
+	if sessionState.RecordCDP && ContextsBucketName != "" {
+		env = append(env,
+			ecstypes.KeyValuePair{Name: aws.String("CDP_RECORDING_ENABLED"), Value: aws.String("true")},
+			ecstypes.KeyValuePair{Name: aws.String("CDP_RECORDING_S3_KEY"), Value: aws.String(CDPRecordingS3Key(sessionState.ProjectID, sessionID))},
+		)
+	}
+
+	if sessionState.RecordSession && ContextsBucketName != "" {
+		env = append(env, ecstypes.KeyValuePair{Name: aws.String("SESSION_RECORDING_ENABLED"), Value: aws.String("true")})
+	}
+
+	if sessionState.RecordScreencast && ContextsBucketName != "" {
+		fps := sessionState.ScreencastFPS
+		if fps <= 0 {
+			fps = DefaultScreencastFPS
+		}
+		env = append(env,
+			ecstypes.KeyValuePair{Name: aws.String("SCREENCAST_ENABLED"), Value: aws.String("true")},
+			ecstypes.KeyValuePair{Name: aws.String("SCREENCAST_S3_KEY"), Value: aws.String(ScreencastS3Key(sessionState.ProjectID, sessionID))},
+			ecstypes.KeyValuePair{Name: aws.String("SCREENCAST_FPS"), Value: aws.String(strconv.FormatFloat(fps, 'f', -1, 64))},
+		)
+	}
+
+	// Browser settings are applied by the controller through Chrome flags and Emulation.* calls
+	if !IsEmptyBrowserSettings(sessionState.BrowserSettings) {
+		browserSettingsJSON, _ := json.Marshal(sessionState.BrowserSettings)
+		env = append(env, ecstypes.KeyValuePair{
+			Name:  aws.String("BROWSER_SETTINGS"),
+			Value: aws.String(string(browserSettingsJSON)),
+		})
+	}
+
+	// Resource limits are enforced by the controller
+	if sessionState.ResourceLimits != nil {
+		limitsJSON, _ := json.Marshal(sessionState.ResourceLimits)
+		env = append(env, ecstypes.KeyValuePair{
+			Name:  aws.String("RESOURCE_LIMITS"),
+			Value: aws.String(string(limitsJSON)),
+		})
+	}
+
+	// The project's CDP method policy applies to every client, whatever its token carries
+	if sessionState.CDPPolicy != nil {
+		policyJSON, _ := json.Marshal(sessionState.CDPPolicy)
+		env = append(env, ecstypes.KeyValuePair{
+			Name:  aws.String("CDP_POLICY"),
+			Value: aws.String(string(policyJSON)),
+		})
+	}
+
+	// Upstream proxies are served by the controller's local forwarding proxy
+	if len(sessionState.Proxies) > 0 {
+		proxiesJSON, _ := json.Marshal(sessionState.Proxies)
+		env = append(env, ecstypes.KeyValuePair{
+			Name:  aws.String("PROXY_CONFIG"),
+			Value: aws.String(string(proxiesJSON)),
+		})
+	}
+
 	// Add model config if available
 	if sessionState.ModelConfig != nil {
 		modelConfigJSON, _ := json.Marshal(sessionState.ModelConfig)
@@ -479,6 +598,12 @@ func CreateECSTask(ctx context.Context, sessionID string, sessionState *types.Se
 
 // StopECSTask stops an ECS task
 func StopECSTask(ctx context.Context, taskARN string) error {
+	return StopECSTaskWithReason(ctx, taskARN, TaskStopReasonEnded)
+}
+
+// StopECSTaskWithReason stops an ECS task. The reason is reported back as the task's
+// stoppedReason, which ecs-task-processor uses to pick the session's final status.
+func StopECSTaskWithReason(ctx context.Context, taskARN, reason string) error {
 	cfg, err := GetAWSConfig()
 	if err != nil {
 		return err
@@ -489,7 +614,7 @@ func StopECSTask(ctx context.Context, taskARN string) error {
 	_, err = ecsClient.StopTask(ctx, &ecs.StopTaskInput{
 		Cluster: aws.String(ECSCluster),
 		Task:    aws.String(taskARN),
-		Reason:  aws.String("Session ended"),
+		Reason:  aws.String(reason),
 	})
 
 	return err
@@ -763,72 +888,87 @@ func GetENIPublicIP(ctx context.Context, eniID string) (string, error) {
 
 // CreateAuthenticatedCDPURL creates the authenticated CDP WebSocket URL for Direct Mode
 func CreateAuthenticatedCDPURL(taskIP, jwtToken string) string {
-	// Get CDP proxy port from environment (set by CDK)
-	cdpProxyPort := os.Getenv("CDP_PROXY_PORT")
-	if cdpProxyPort == "" {
-		cdpProxyPort = "9223" // Fallback to default
-	}
 	// Match Browserbase format: ws://host:port?signingKey=token (no /cdp path)
-	return fmt.Sprintf("ws://%s:%s?signingKey=%s", taskIP, cdpProxyPort, jwtToken)
+	return fmt.Sprintf("ws://%s?signingKey=%s", cdpProxyAddress(taskIP), jwtToken)
+}
+
+// CreateControllerURL creates an authenticated HTTP URL for a path on the controller's CDP proxy
+func CreateControllerURL(taskIP, path, jwtToken string) string {
+	params := url.Values{}
+	params.Set("signingKey", jwtToken)
+	return fmt.Sprintf("http://%s%s?%s", cdpProxyAddress(taskIP), path, params.Encode())
 }
 
-// CreateDebuggerURL creates the Chrome DevTools debugger URL for web-based debugging
+// CreateDebuggerURL creates the live view URL served by the session's controller
 func CreateDebuggerURL(taskIP, jwtToken string) string {
-	// Get CDP proxy port from environment (set by CDK)
-	cdpProxyPort := os.Getenv("CDP_PROXY_PORT")
-	if cdpProxyPort == "" {
-		cdpProxyPort = "9223" // Fallback to default
+	return createLiveViewURL(taskIP, jwtToken, "/live", "", false)
+}
+
+// CreateDebuggerFullscreenURL creates the live view URL without the page toolbar, for embedding
+func CreateDebuggerFullscreenURL(taskIP, jwtToken string) string {
+	return createLiveViewURL(taskIP, jwtToken, "/live", "", true)
+}
+
+// CreatePageLiveViewURL creates a live view URL pinned to a single page target
+func CreatePageLiveViewURL(taskIP, jwtToken, targetID string, fullscreen bool) string {
+	return createLiveViewURL(taskIP, jwtToken, "/live", targetID, fullscreen)
+}
+
+// CreatePageDebuggerURL creates a DevTools URL for a single page target. Both the frontend,
+// bundled with the session's Chrome, and its /devtools/page/{id} socket go through the CDP proxy.
+// The proxy sets a cookie on the inspector.html response so the frontend's assets load without
+// the signing key in their URLs.
+func CreatePageDebuggerURL(taskIP, jwtToken, targetID string) string {
+	address := cdpProxyAddress(taskIP)
+
+	params := url.Values{}
+	params.Set("ws", fmt.Sprintf("%s/devtools/page/%s?signingKey=%s", address, targetID, url.QueryEscape(jwtToken)))
+	params.Set("signingKey", jwtToken)
+
+	return fmt.Sprintf("http://%s/devtools/inspector.html?%s", address, params.Encode())
+}
+
+// CreateTakeoverURL creates the interactive takeover URL, where a human can pause automation and control the browser
+func CreateTakeoverURL(taskIP, jwtToken string) string {
+	return createLiveViewURL(taskIP, jwtToken, "/takeover", "", false)
+}
+
+// createLiveViewURL builds http://host:port/{path}?signingKey=... on the controller's CDP proxy port.
+// Without a target the view follows the active tab.
+func createLiveViewURL(taskIP, jwtToken, path, targetID string, fullscreen bool) string {
+	params := url.Values{}
+	params.Set("signingKey", jwtToken)
+	if targetID != "" {
+		params.Set("target", targetID)
+	}
+	if fullscreen {
+		params.Set("fullscreen", "true")
 	}
 
-	// Use Chrome DevTools frontend hosted on chrome-devtools-frontend.appspot.com
-	// This is the standard way to create debugger URLs for remote Chrome instances
-	wsURL := fmt.Sprintf("%s:%s", taskIP, cdpProxyPort)
-	return fmt.Sprintf("https://chrome-devtools-frontend.appspot.com/serve_file/@66a71dd84e44ed89c31a91e3a53006a7a6e1b72e/inspector.html?ws=%s&signingKey=%s",
-		wsURL, jwtToken)
+	return fmt.Sprintf("http://%s%s?%s", cdpProxyAddress(taskIP), path, params.Encode())
 }
 
-// CreateDebuggerFullscreenURL creates the fullscreen Chrome DevTools debugger URL
-func CreateDebuggerFullscreenURL(taskIP, jwtToken string) string {
+// cdpProxyAddress returns host:port of the controller's CDP proxy
+func cdpProxyAddress(taskIP string) string {
 	// Get CDP proxy port from environment (set by CDK)
 	cdpProxyPort := os.Getenv("CDP_PROXY_PORT")
 	if cdpProxyPort == "" {
 		cdpProxyPort = "9223" // Fallback to default
 	}
-
-	// Create fullscreen debugger URL with dockSide=undocked for fullscreen mode
-	wsURL := fmt.Sprintf("%s:%s", taskIP, cdpProxyPort)
-	return fmt.Sprintf("https://chrome-devtools-frontend.appspot.com/serve_file/@66a71dd84e44ed89c31a91e3a53006a7a6e1b72e/inspector.html?ws=%s&signingKey=%s&dockSide=undocked",
-		wsURL, jwtToken)
+	return fmt.Sprintf("%s:%s", taskIP, cdpProxyPort)
 }
 
-// AddSessionEvent adds an event to session history and publishes to EventBridge
+// AddSessionEvent records the event time on the session and publishes the event to
+// EventBridge. Only lastEventTimestamp is written, so concurrent status changes are never
+// overwritten by a stale copy of the record.
 func AddSessionEvent(ctx context.Context, ddbClient *dynamodb.Client, sessionID, eventType, source string, detail map[string]interface{}) error {
-	sessionState, err := GetSession(ctx, ddbClient, sessionID)
+	_, err := updateSession(ctx, ddbClient, sessionID, sessionUpdate{
+		set: []string{"lastEventTimestamp = :now"},
+	})
 	if err != nil {
 		return err
 	}
 
-	now := time.Now()
-	nowStr := now.Format(time.RFC3339)
-	sessionEvent := types.SessionEvent{
-		EventType: eventType,
-		Timestamp: nowStr,
-		Source:    source,
-		Detail:    detail,
-	}
-
-	if sessionState.EventHistory == nil {
-		sessionState.EventHistory = []types.SessionEvent{}
-	}
-	sessionState.EventHistory = append(sessionState.EventHistory, sessionEvent)
-	sessionState.LastEventTimestamp = &nowStr
-	sessionState.UpdatedAt = nowStr
-
-	// Store updated session state
-	if err := StoreSession(ctx, ddbClient, sessionState); err != nil {
-		return err
-	}
-
 	// Publish to EventBridge
 	return PublishEvent(ctx, sessionID, eventType, detail)
 }
@@ -849,10 +989,10 @@ func CreateSessionWithDefaults(sessionID, projectID string, modelConfig *types.M
 
 	// Default resource limits
 	defaultLimits := &types.ResourceLimits{
-		MaxCPU:      1024, // 1 vCPU
-		MaxMemory:   2048, // 2GB
-		MaxDuration: 3600, // 1 hour
-		MaxActions:  1000, // 1000 actions
+		MaxCPU:      1024,           // 1 vCPU
+		MaxMemory:   2048,           // 2GB
+		MaxDuration: timeoutSeconds, // Session timeout
+		MaxActions:  1000,           // 1000 actions
 	}
 
 	// Initialize billing info
@@ -892,23 +1032,115 @@ func IsSessionActive(status string) bool {
 		status == types.SessionStatusStarting
 }
 
-// IsSessionTerminal checks if session is in a terminal state
+// IsSessionTerminal checks if session is in a terminal state (one the transition table has
+// no way out of)
 func IsSessionTerminal(status string) bool {
 	return status == types.SessionStatusStopped ||
-		status == types.SessionStatusFailed
+		status == types.SessionStatusFailed ||
+		status == types.SessionStatusTimedOut
 }
 
 // IncrementSessionRetryCount increments the retry count for a session
 func IncrementSessionRetryCount(ctx context.Context, ddbClient *dynamodb.Client, sessionID string) error {
-	sessionState, err := GetSession(ctx, ddbClient, sessionID)
-	if err != nil {
+	_, err := updateSession(ctx, ddbClient, sessionID, sessionUpdate{
+		set: []string{"retryCount = if_not_exists(retryCount, :zero) + :one"},
+		values: map[string]dynamotypes.AttributeValue{
+			":zero": &dynamotypes.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	return err
+}
+
+// AddSessionProxyBytes atomically adds metered traffic to a session's proxyBytes total.
+// An atomic ADD is used because the controller flushes concurrently with API updates.
+func AddSessionProxyBytes(ctx context.Context, ddbClient *dynamodb.Client, sessionID string, delta int64) error {
+	if delta <= 0 {
+		return nil
+	}
+
+	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(SessionsTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"sessionId": &dynamotypes.AttributeValueMemberS{Value: sessionID},
+		},
+		UpdateExpression:    aws.String("ADD proxyBytes :delta, version :one SET updatedAt = :now"),
+		ConditionExpression: aws.String("attribute_exists(sessionId)"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":delta": &dynamotypes.AttributeValueMemberN{Value: strconv.FormatInt(delta, 10)},
+			":one":   &dynamotypes.AttributeValueMemberN{Value: "1"},
+			":now":   &dynamotypes.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+	})
+	return err
+}
+
+// SessionResourceUsage is the controller's resource sampling summary for a session
+type SessionResourceUsage struct {
+	AvgCPUPercent int     // Average browser CPU, percent of one core
+	PeakMemoryMB  int     // Peak browser memory
+	CPUSeconds    float64 // Container CPU time since the session started
+	MemoryMBHours float64 // Container memory integrated over time
+	ActionsCount  int     // Actions sent through the CDP proxy
+}
+
+// UpdateSessionResourceUsage writes resource usage totals to the session and its billing info.
+// The totals are absolute, so a failed write is corrected by the next one.
+func UpdateSessionResourceUsage(ctx context.Context, ddbClient *dynamodb.Client, sessionID string, usage SessionResourceUsage) error {
+	now := time.Now()
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(SessionsTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"sessionId": &dynamotypes.AttributeValueMemberS{Value: sessionID},
+		},
+		UpdateExpression: aws.String("SET avgCpuUsage = :cpu, memoryUsage = :mem, " +
+			"billingInfo.cpuSeconds = :cpuSeconds, billingInfo.memoryMBHours = :mbHours, " +
+			"billingInfo.actionsCount = :actions, billingInfo.lastBillingAt = :billedAt, updatedAt = :now ADD version :one"),
+		ConditionExpression: aws.String("attribute_exists(sessionId)"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":cpu":        &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(usage.AvgCPUPercent)},
+			":mem":        &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(usage.PeakMemoryMB)},
+			":cpuSeconds": &dynamotypes.AttributeValueMemberN{Value: strconv.FormatFloat(usage.CPUSeconds, 'f', 3, 64)},
+			":mbHours":    &dynamotypes.AttributeValueMemberN{Value: strconv.FormatFloat(usage.MemoryMBHours, 'f', 3, 64)},
+			":actions":    &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(usage.ActionsCount)},
+			":billedAt":   &dynamotypes.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			":now":        &dynamotypes.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
+			":one":        &dynamotypes.AttributeValueMemberN{Value: "1"},
+		},
+	}
+
+	_, err := ddbClient.UpdateItem(ctx, input)
+	if err == nil {
+		return nil
+	}
+
+	// Sessions written without billing info have no map to update into; create it and retry once
+	if initErr := initSessionBillingInfo(ctx, ddbClient, sessionID, now); initErr != nil {
 		return err
 	}
+	_, err = ddbClient.UpdateItem(ctx, input)
+	return err
+}
 
-	sessionState.RetryCount++
-	sessionState.UpdatedAt = time.Now().Format(time.RFC3339)
+// initSessionBillingInfo creates an empty billingInfo map if the session has none
+func initSessionBillingInfo(ctx context.Context, ddbClient *dynamodb.Client, sessionID string, now time.Time) error {
+	billingAV, err := attributevalue.Marshal(&types.BillingInfo{LastBillingAt: now.UTC()})
+	if err != nil {
+		return err
+	}
 
-	return StoreSession(ctx, ddbClient, sessionState)
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(SessionsTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"sessionId": &dynamotypes.AttributeValueMemberS{Value: sessionID},
+		},
+		UpdateExpression:    aws.String("SET billingInfo = if_not_exists(billingInfo, :billing) ADD version :one"),
+		ConditionExpression: aws.String("attribute_exists(sessionId)"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":billing": billingAV,
+			":one":     &dynamotypes.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	return err
 }
 
 // MapStatusToSDK converts internal session status to SDK-compatible status